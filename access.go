@@ -0,0 +1,106 @@
+package main
+
+import (
+	"image/color"
+
+	"ebiten/Snake/sim"
+
+	"github.com/hajimehoshi/ebiten"
+	"github.com/hajimehoshi/ebiten/ebitenutil"
+)
+
+// speeds are how fast, in percent, the speed assist can have a local game go.
+var speeds = []int{100, 75, 50, 25}
+
+// speedAssist returns how fast the player has asked a local game to go, in
+// percent.
+func (g *Game) speedAssist() int {
+	if g.prefs.Speed <= 0 || g.prefs.Speed > 100 {
+		return 100
+	}
+	return g.prefs.Speed
+}
+
+// switchSpeed moves step speeds on.
+func (g *Game) switchSpeed(step int) {
+	i := 0
+	for j, s := range speeds {
+		if s == g.speedAssist() {
+			i = j
+		}
+	}
+	g.prefs.Speed = speeds[(i+step+len(speeds))%len(speeds)]
+}
+
+// assisted reports whether the game runs this frame as far as the speed
+// assist goes: at 75% it sits out every fourth frame, at 50% every other.
+func (g *Game) assisted() bool {
+	g.assist += g.speedAssist()
+	if g.assist < 100 {
+		return false
+	}
+	g.assist -= 100
+	return true
+}
+
+// applyPractice turns practice on or off for a local game. Challenges,
+// replays and network games are always played for real.
+func (g *Game) applyPractice() {
+	if g.world != nil && g.prefs != nil && g.spectator == nil && g.canStep() {
+		g.world.Practice = g.prefs.Practice
+	}
+}
+
+// drawCell fills a cell of the board, outlined in the colour of the text
+// when outlines are on.
+func (g *Game) drawCell(board *ebiten.Image, p sim.Position, c color.Color) {
+	x, y := float64(p.X*gridSize), float64(p.Y*gridSize)
+	ebitenutil.DrawRect(board, x, y, gridSize, gridSize, c)
+	if g.prefs.Outlines {
+		outline(board, x, y, gridSize, g.theme.HUD.Text)
+	}
+}
+
+// outline draws the edge of a square size across.
+func outline(board *ebiten.Image, x, y, size float64, c color.Color) {
+	ebitenutil.DrawRect(board, x, y, size, 1, c)
+	ebitenutil.DrawRect(board, x, y+size-1, size, 1, c)
+	ebitenutil.DrawRect(board, x, y+1, 1, size-2, c)
+	ebitenutil.DrawRect(board, x+size-1, y+1, 1, size-2, c)
+}
+
+// drawFood draws food of a kind, in a shape of its own when shapes are on:
+//
+//	apple    a square
+//	golden   a plus
+//	poison   a cross
+//	rabbit   a ring
+//	remains  a checkerboard
+func (g *Game) drawFood(board *ebiten.Image, p sim.Position, kind string, c color.Color) {
+	if !g.prefs.FoodShapes || kind == sim.FoodApple {
+		g.drawCell(board, p, c)
+		return
+	}
+	x, y := float64(p.X*gridSize), float64(p.Y*gridSize)
+	const third = gridSize / 3
+	switch kind {
+	case sim.FoodGolden:
+		ebitenutil.DrawRect(board, x, y+third, gridSize, gridSize-2*third, c)
+		ebitenutil.DrawRect(board, x+third, y, gridSize-2*third, gridSize, c)
+	case sim.FoodPoison:
+		for d := 0.0; d < 2; d++ {
+			ebitenutil.DrawLine(board, x+d, y, x+gridSize, y+gridSize-d, c)
+			ebitenutil.DrawLine(board, x+gridSize-d, y, x, y+gridSize-d, c)
+		}
+	case sim.FoodRabbit:
+		outline(board, x, y, gridSize, c)
+		outline(board, x+1, y+1, gridSize-2, c)
+	default:
+		half := float64(gridSize / 2)
+		ebitenutil.DrawRect(board, x, y, half, half, c)
+		ebitenutil.DrawRect(board, x+half, y+half, half, half, c)
+	}
+	if g.prefs.Outlines {
+		outline(board, x, y, gridSize, g.theme.HUD.Text)
+	}
+}
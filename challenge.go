@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"ebiten/Snake/netplay"
+	"ebiten/Snake/scores"
+	"ebiten/Snake/sim"
+
+	"github.com/hajimehoshi/ebiten"
+)
+
+// challengeTable names the high-score table of the challenge being played.
+// Every time limit and sprint length gets a table of its own.
+func (g *Game) challengeTable() string {
+	w := g.world
+	if g.daily != "" {
+		return netplay.DailyTable(g.daily)
+	}
+	switch w.Mode {
+	case sim.ModeTimeAttack:
+		return fmt.Sprintf("timeattack-%ds", w.TimeLimit/ebiten.MaxTPS())
+	case sim.ModeSprint:
+		return fmt.Sprintf("sprint-%d", w.SprintLength)
+	}
+	return w.Mode
+}
+
+// record enters the finished challenge into its high-score table once.
+func (g *Game) record() {
+	p := g.player()
+	if g.recorded || g.scores == nil || p == nil || !g.world.Over {
+		return
+	}
+	g.recorded = true
+	if g.world.Mode == sim.ModeSprint && len(p.Body) < g.world.SprintLength {
+		// A bot got there first, there is no time to record.
+		return
+	}
+	e := scores.Entry{Name: g.name, Score: g.world.ChallengeScore(p)}
+	if g.replay != nil {
+		var err error
+		if e.Replay, err = g.scores.SaveReplay(g.challengeTable(), g.replay); err != nil {
+			log.Println(err)
+		}
+		if g.board != "" {
+			g.boardMsg = "Sending the result to the team"
+			go g.submit(g.replay)
+		}
+	}
+	g.rank = g.scores.Add(g.challengeTable(), g.world.LowerIsBetter(), e)
+	if err := g.scores.Save(); err != nil {
+		log.Println(err)
+	}
+}
+
+// boardResult is what the shared daily leaderboard made of a game.
+type boardResult struct {
+	table []scores.Entry
+	msg   string
+}
+
+// submit sends a daily challenge to the shared leaderboard and fetches the
+// day's table back.
+func (g *Game) submit(rp *sim.Replay) {
+	res, err := netplay.SubmitDaily(g.board, rp)
+	if err != nil {
+		g.boardResult <- boardResult{msg: err.Error()}
+		return
+	}
+	msg := fmt.Sprintf("%d apples on the team's table", res.Score)
+	if res.Rank > 0 {
+		msg = fmt.Sprintf("Number %d on the team's table", res.Rank)
+	}
+	table, err := netplay.FetchDaily(g.board, rp.Date)
+	if err != nil {
+		msg = err.Error()
+	}
+	g.boardResult <- boardResult{table: table, msg: msg}
+}
+
+// clock formats frames as minutes, seconds and tenths.
+func clock(frames int) string {
+	if frames < 0 {
+		frames = 0
+	}
+	tenths := frames * 10 / ebiten.MaxTPS()
+	return fmt.Sprintf("%d:%02d.%d", tenths/600, tenths/10%60, tenths%10)
+}
+
+// challengeStatus is the timer line of a challenge.
+func (g *Game) challengeStatus(p *sim.Snake) string {
+	w := g.world
+	switch w.Mode {
+	case sim.ModeTimeAttack:
+		return fmt.Sprintf("Time left %s  Apples %d", clock(w.TimeLeft()), p.Score)
+	case sim.ModeSprint:
+		return fmt.Sprintf("Time %s  Length %d/%d", clock(w.Clock), len(p.Body), w.SprintLength)
+	case sim.ModeSurvival:
+		return fmt.Sprintf("Apple goes bad in %s  Apples %d", clock(w.TimeLeft()), p.Score)
+	}
+	return ""
+}
+
+// challengeResult says how the challenge went and shows its table.
+func (g *Game) challengeResult() string {
+	w := g.world
+	p := g.player()
+	var b strings.Builder
+	switch {
+	case p == nil:
+	case w.Mode == sim.ModeSprint && w.Winner != g.id:
+		b.WriteString("Beaten to it")
+	case w.Mode == sim.ModeSprint:
+		fmt.Fprintf(&b, "Length %d in %s", w.SprintLength, clock(w.Clock))
+	case w.Mode == sim.ModeSurvival && p.Dead:
+		fmt.Fprintf(&b, "Crashed with %d apples", p.Score)
+	case w.Mode == sim.ModeSurvival:
+		fmt.Fprintf(&b, "The apple went bad, %d apples", p.Score)
+	default:
+		fmt.Fprintf(&b, "Time's up, %d apples", p.Score)
+	}
+	if g.rank > 0 {
+		fmt.Fprintf(&b, ", number %d on the table", g.rank)
+	}
+	b.WriteString(", press Escape to play again\n\n")
+
+	if g.boardMsg != "" {
+		fmt.Fprintf(&b, "%s\n\n", g.boardMsg)
+	}
+	if g.scores == nil {
+		return b.String()
+	}
+	title := "Best of " + g.challengeTable()
+	entries, rank := g.scores.Table(g.challengeTable()), g.rank
+	if g.dailyTable != nil {
+		title = "Best of the team on " + g.daily
+		entries, rank = g.dailyTable, 0
+	}
+	b.WriteString(title + "\n")
+	for i, e := range entries {
+		mark := " "
+		if i+1 == rank {
+			mark = ">"
+		}
+		score := fmt.Sprint(e.Score)
+		if w.LowerIsBetter() {
+			score = clock(e.Score)
+		}
+		fmt.Fprintf(&b, "%s %2d. %-16s %8s  %s\n", mark, i+1, e.Name, score, e.Date)
+	}
+	return b.String()
+}
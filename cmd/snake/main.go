@@ -0,0 +1,173 @@
+// Command snake runs the parts of the game that need no window.
+//
+//	snake server [-addr :7070] [-ws :7071] [-players 4] [-daily daily.json]
+//	snake bot [-connect localhost:7070] [-name bot] [-room 0]
+//	snake web [-addr :8080] [-dir dist] [-daily daily.json]
+//	snake pwa [-dir dist]
+//	snake --tui [-mode classic] [-bots 0] [-ai] [-watch ws://host:port/watch]
+//
+// The server's -ws address takes WebSocket players at /ws, read-only
+// spectators at /watch and replays of the daily challenge at /daily. web
+// serves the browser build made by `make web`, with the daily challenge
+// leaderboard next to it, and pwa makes that build installable. --tui, or
+// tui, plays or watches in the terminal.
+//
+// It lives apart from the windowed game because ebiten needs a display as soon
+// as it is imported.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	"ebiten/Snake/netplay"
+	"ebiten/Snake/sim"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: snake server|bot|web|pwa|--tui [flags]")
+	os.Exit(2)
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	switch os.Args[1] {
+	case "server":
+		runServer(os.Args[2:])
+	case "bot":
+		runBot(os.Args[2:])
+	case "web":
+		runWeb(os.Args[2:])
+	case "pwa":
+		runPWA(os.Args[2:])
+	case "tui", "-tui", "--tui":
+		runTUI(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func runServer(args []string) {
+	fs := flag.NewFlagSet("server", flag.ExitOnError)
+	addr := fs.String("addr", ":7070", "TCP address to listen on")
+	wsAddr := fs.String("ws", ":7071", "HTTP address for WebSocket clients at /ws, empty to disable")
+	players := fs.Int("players", 4, "maximum number of players in a room")
+	rooms := fs.Int("rooms", 8, "maximum number of rooms")
+	daily := fs.String("daily", "daily.json", "file to keep the daily challenge leaderboard in, empty to keep it in memory")
+	fs.Parse(args)
+
+	srv := netplay.NewServer(*players, *rooms)
+
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("listening on %s", l.Addr())
+	go func() {
+		log.Fatal(srv.Serve(l))
+	}()
+	if *wsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/ws", srv)
+		mux.Handle("/watch", srv.Spectators())
+		board, err := netplay.NewDailyBoard(*daily)
+		if err != nil {
+			log.Fatal(err)
+		}
+		mux.Handle("/daily", board)
+		log.Printf("websocket on ws://%s/ws, spectators on ws://%s/watch, daily challenge on http://%s/daily", *wsAddr, *wsAddr, *wsAddr)
+		go func() {
+			log.Fatal(http.ListenAndServe(*wsAddr, mux))
+		}()
+	}
+	srv.Run()
+}
+
+// runBot joins a server and lets the server's AI play, which is handy for
+// filling a board when trying the game on localhost.
+func runBot(args []string) {
+	fs := flag.NewFlagSet("bot", flag.ExitOnError)
+	addr := fs.String("connect", "localhost:7070", "server address, host:port or ws://host:port/ws")
+	name := fs.String("name", "bot", "player name")
+	room := fs.Int("room", 0, "room to join, 0 for the first one with space")
+	fs.Parse(args)
+
+	c, err := netplay.Connect(*addr, *name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer c.Close()
+	if err := joinRoom(c, *room); err != nil {
+		log.Fatal(err)
+	}
+
+	t := time.NewTicker(time.Second / netplay.TicksPerSecond)
+	defer t.Stop()
+	ready, ai := false, false
+	for range t.C {
+		w, err := c.Update()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if msg := c.Refused(); msg != "" {
+			log.Fatal(msg)
+		}
+		if w == nil {
+			if r := c.Room(); r != nil && r.ID != 0 && !ready {
+				log.Printf("joined room %d (%s)", r.ID, r.Name)
+				c.Ready(true)
+				ready = true
+			}
+			continue
+		}
+		if !ai {
+			c.ToggleAI()
+			ai = true
+		}
+		// The AI only steers a snake that is already moving.
+		if s := w.Snake(c.ID); s != nil && !s.Dead && s.Dir == sim.DirNone {
+			c.Turn(sim.DirRight)
+		}
+	}
+}
+
+// runWeb serves the game to browsers, which is all it takes to try the
+// browser build locally.
+func runWeb(args []string) {
+	fs := flag.NewFlagSet("web", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "HTTP address to listen on")
+	dir := fs.String("dir", "dist", "directory with index.html, wasm_exec.js and main.wasm")
+	daily := fs.String("daily", "", "file to keep the daily challenge leaderboard in, empty to keep it in memory")
+	fs.Parse(args)
+
+	board, err := netplay.NewDailyBoard(*daily)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/", http.FileServer(http.Dir(*dir)))
+	mux.Handle("/daily", board)
+	log.Printf("serving %s on http://%s/, daily challenge on http://%s/daily", *dir, *addr, *addr)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+// joinRoom joins the room with the given id, or with 0 the first one with
+// space left, creating one if there is none.
+func joinRoom(c *netplay.Client, id int) error {
+	if id != 0 {
+		return c.Join(id)
+	}
+	for _, r := range c.Rooms() {
+		if len(r.Players) < r.Settings.MaxPlayers {
+			return c.Join(r.ID)
+		}
+	}
+	return c.Create("", netplay.DefaultSettings)
+}
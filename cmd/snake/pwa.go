@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// pwaFiles are the files of the browser build the service worker keeps for
+// offline play, besides the ones runPWA writes.
+var pwaFiles = []string{"index.html", "wasm_exec.js", "main.wasm"}
+
+// pwaIcons are the sizes of the icons the manifest offers.
+var pwaIcons = []int{192, 512}
+
+// runPWA turns the browser build in a directory into an app that installs to
+// the home screen and plays offline: it writes the manifest, the icons and a
+// service worker whose cache is named after a hash of the build, so a new
+// build replaces the old one the next time the game is opened.
+func runPWA(args []string) {
+	fs := flag.NewFlagSet("pwa", flag.ExitOnError)
+	dir := fs.String("dir", "dist", "directory with the browser build")
+	fs.Parse(args)
+
+	h := sha256.New()
+	for _, name := range pwaFiles {
+		data, err := ioutil.ReadFile(filepath.Join(*dir, name))
+		if err != nil {
+			log.Fatal(err)
+		}
+		h.Write(data)
+	}
+	version := hex.EncodeToString(h.Sum(nil))[:12]
+
+	files := append([]string{"./", "manifest.webmanifest"}, pwaFiles...)
+	type icon struct {
+		Src   string `json:"src"`
+		Sizes string `json:"sizes"`
+		Type  string `json:"type"`
+	}
+	var icons []icon
+	for _, size := range pwaIcons {
+		name := fmt.Sprintf("icon-%d.png", size)
+		if err := writeIcon(filepath.Join(*dir, name), size); err != nil {
+			log.Fatal(err)
+		}
+		files = append(files, name)
+		icons = append(icons, icon{Src: name, Sizes: fmt.Sprintf("%dx%d", size, size), Type: "image/png"})
+	}
+
+	manifest, err := json.MarshalIndent(map[string]interface{}{
+		"name":             "Snake",
+		"short_name":       "Snake",
+		"start_url":        "./",
+		"display":          "standalone",
+		"background_color": "#000000",
+		"theme_color":      "#181820",
+		"icons":            icons,
+	}, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(*dir, "manifest.webmanifest"), manifest, 0644); err != nil {
+		log.Fatal(err)
+	}
+
+	list, _ := json.Marshal(files)
+	sw := strings.NewReplacer("{{version}}", version, "{{files}}", string(list)).Replace(serviceWorker)
+	if err := ioutil.WriteFile(filepath.Join(*dir, "sw.js"), []byte(sw), 0644); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintf(os.Stderr, "%s: version %s\n", *dir, version)
+}
+
+// serviceWorker answers from the cache of this version first, drops the
+// caches of older ones once it takes over, and leaves the daily challenge
+// leaderboard to the network.
+const serviceWorker = `// Written by snake pwa for version {{version}}, don't edit.
+const CACHE = "snake-{{version}}";
+const FILES = {{files}};
+
+self.addEventListener("install", e => {
+	e.waitUntil(caches.open(CACHE).then(c => c.addAll(FILES)).then(() => self.skipWaiting()));
+});
+
+self.addEventListener("activate", e => {
+	e.waitUntil(caches.keys()
+		.then(keys => Promise.all(keys.filter(k => k.startsWith("snake-") && k !== CACHE).map(k => caches.delete(k))))
+		.then(() => self.clients.claim()));
+});
+
+self.addEventListener("fetch", e => {
+	const url = new URL(e.request.url);
+	if (e.request.method !== "GET" || url.origin !== location.origin || url.pathname.endsWith("/daily")) {
+		return;
+	}
+	// The page's address carries flags, the cache doesn't care about them.
+	e.respondWith(caches.open(CACHE)
+		.then(c => c.match(e.request, {ignoreSearch: true}))
+		.then(r => r || fetch(e.request)));
+});
+`
+
+// writeIcon draws a snake going for an apple in the colours of the game.
+func writeIcon(path string, size int) error {
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), &image.Uniform{color.RGBA{0x18, 0x18, 0x20, 0xff}}, image.Point{}, draw.Src)
+	cell := size / 8
+	fill := func(x, y int, c color.RGBA) {
+		r := image.Rect(x*cell+cell/16, y*cell+cell/16, (x+1)*cell-cell/16, (y+1)*cell-cell/16)
+		draw.Draw(img, r, &image.Uniform{c}, image.Point{}, draw.Src)
+	}
+	for _, p := range [][2]int{{1, 2}, {2, 2}, {3, 2}, {4, 2}, {5, 2}, {5, 3}, {5, 4}, {4, 4}, {3, 4}, {2, 4}, {2, 5}} {
+		fill(p[0], p[1], color.RGBA{0x80, 0xa0, 0xc0, 0xff})
+	}
+	fill(5, 6, color.RGBA{0xff, 0x00, 0x00, 0xff})
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
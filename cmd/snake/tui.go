@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"ebiten/Snake/netplay"
+	"ebiten/Snake/settings"
+	"ebiten/Snake/sim"
+	"ebiten/Snake/theme"
+)
+
+// tuiFrames is how many frames pass between two pictures at most. The
+// terminal doesn't need sixty a second, least of all over SSH.
+const tuiFrames = 3
+
+// tui is a game in the terminal: a local one, or one being watched.
+type tui struct {
+	world *sim.World
+	id    int
+
+	cfg  sim.Config
+	bots int
+	ai   bool
+
+	spectator *netplay.Spectator
+	path      []sim.Position
+
+	theme *theme.Theme
+}
+
+// runTUI plays in the terminal with the same rules as the window, drawing
+// two cells a character with half blocks, or watches a game with -watch.
+func runTUI(args []string) {
+	fs := flag.NewFlagSet("tui", flag.ExitOnError)
+	mode := fs.String("mode", sim.ModeClassic, "classic, royale, timeattack, sprint or survival")
+	bots := fs.Int("bots", 0, "number of AI snakes to play against")
+	ai := fs.Bool("ai", false, "let the AI play the player's snake too")
+	wrap := fs.Bool("wrap", false, "wrap around the edges of the board")
+	layout := fs.String("layout", sim.LayoutOpen, "what is on the board: "+strings.Join(sim.LayoutNames(), ", "))
+	powerUps := fs.Bool("powerups", false, "let power-ups turn up on the board")
+	foodFlag := fs.String("food", "", "food on the board at once besides the apple, e.g. "+sim.FormatFood(sim.DefaultFood))
+	width := fs.Int("width", 0, "board width, as much as the terminal has room for up to 64 if 0")
+	height := fs.Int("height", 0, "board height, as much as the terminal has room for up to 48 if 0")
+	watch := fs.String("watch", "", "watch a game instead, ws://host:port/watch")
+	follow := fs.Int("follow", 1, "id of the snake to follow when watching")
+	themeName := fs.String("theme", "", "theme to draw in, the one picked in the window if none")
+	fs.Parse(args)
+
+	food, err := sim.ParseFood(*foodFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, ok := sim.Layouts[*layout]; !ok {
+		log.Fatalf("unknown layout %q", *layout)
+	}
+	if *mode == sim.ModeRoyale && *bots == 0 {
+		*bots = 3
+	}
+	t := &tui{id: 1, bots: *bots, ai: *ai, theme: loadTheme(*themeName)}
+	if *watch != "" {
+		if t.spectator, err = netplay.Watch(*watch, *follow); err != nil {
+			log.Fatal(err)
+		}
+		t.id = *follow
+	} else {
+		cols, rows := terminalSize()
+		if *width == 0 {
+			*width = min(64, cols)
+		}
+		if *height == 0 {
+			*height = min(48, 2*(rows-2))
+		}
+		if *width < netplay.MinBoardWidth || *height < netplay.MinBoardHeight {
+			// Smaller boards have no room for the layouts or the snakes.
+			log.Fatalf("the board must be at least %dx%d, not %dx%d: make the terminal bigger or pick a size",
+				netplay.MinBoardWidth, netplay.MinBoardHeight, *width, *height)
+		}
+		t.cfg = sim.Config{
+			Width: *width, Height: *height, Seed: time.Now().UnixNano(), Wrap: *wrap,
+			Mode: *mode, PowerUps: *powerUps, Food: food, Layout: *layout,
+		}
+		t.restart()
+	}
+
+	restore, err := rawTerminal()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer restore()
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	keys := make(chan byte, 16)
+	go readKeys(keys)
+
+	// Clear the screen and hide the cursor, and show it again on the way out.
+	fmt.Print("\x1b[2J\x1b[?25l")
+	defer fmt.Print("\x1b[0m\x1b[?25h\n")
+
+	ticker := time.NewTicker(time.Second / netplay.TicksPerSecond)
+	defer ticker.Stop()
+	frame := 0
+	for {
+		select {
+		case <-signals:
+			return
+		case k, ok := <-keys:
+			if !ok || k == 'q' {
+				return
+			}
+			t.key(k)
+		case <-ticker.C:
+			if err := t.update(); err != nil {
+				restore()
+				log.Fatal(err)
+			}
+			if frame++; frame%tuiFrames == 0 {
+				os.Stdout.Write(t.draw())
+			}
+		}
+	}
+}
+
+// loadTheme returns the theme called name, or the one the player picked in
+// the window.
+func loadTheme(name string) *theme.Theme {
+	themes, err := theme.Load(theme.Path())
+	if err != nil {
+		log.Println(err)
+	}
+	if name == "" {
+		prefs, err := settings.Load(settings.Path())
+		if err != nil {
+			log.Println(err)
+		}
+		name = prefs.Theme
+	} else if theme.Find(themes, name).Name != name {
+		log.Fatalf("unknown theme %q", name)
+	}
+	return theme.Find(themes, name)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func (t *tui) restart() {
+	t.world = sim.NewWorld(t.cfg)
+	s := t.world.AddSnake(t.id)
+	s.Name = "player"
+	if t.ai {
+		s.AI, s.Dir = true, sim.DirRight
+	}
+	for i := 1; i <= t.bots; i++ {
+		b := t.world.AddSnake(t.id + i)
+		b.Name = fmt.Sprintf("Bot %d", i)
+		b.AI = true
+	}
+}
+
+// Keys as readKeys passes them on, besides plain characters.
+const (
+	keyUp byte = 0x80 + iota
+	keyDown
+	keyRight
+	keyLeft
+)
+
+// readKeys passes on every key pressed on stdin, arrows as one byte each.
+func readKeys(keys chan<- byte) {
+	buf := make([]byte, 64)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			close(keys)
+			return
+		}
+		for i := 0; i < n; i++ {
+			if buf[i] == 0x1b && i+2 < n && (buf[i+1] == '[' || buf[i+1] == 'O') && buf[i+2] >= 'A' && buf[i+2] <= 'D' {
+				keys <- keyUp + buf[i+2] - 'A'
+				i += 2
+				continue
+			}
+			keys <- buf[i]
+		}
+	}
+}
+
+// key handles a key: arrows or WASD steer, space hands the snake to the AI
+// and r starts over. Tab follows the next snake when watching.
+func (t *tui) key(k byte) {
+	if t.spectator != nil {
+		if k == '\t' {
+			t.followNext()
+		}
+		return
+	}
+	var in sim.Input
+	switch k {
+	case keyLeft, 'a', 'h':
+		in.Dir = sim.DirLeft
+	case keyRight, 'd', 'l':
+		in.Dir = sim.DirRight
+	case keyDown, 's', 'j':
+		in.Dir = sim.DirDown
+	case keyUp, 'w', 'k':
+		in.Dir = sim.DirUp
+	case ' ':
+		in.ToggleAI = true
+	case 'r':
+		in.Reset = true
+	}
+	if in.Reset && t.world.Mode != sim.ModeClassic {
+		t.restart()
+		return
+	}
+	t.world.Apply(t.id, in)
+}
+
+func (t *tui) followNext() {
+	w := t.world
+	if w == nil || len(w.Snakes) == 0 {
+		return
+	}
+	next := w.Snakes[0].ID
+	for i, s := range w.Snakes {
+		if s.ID == t.id && i+1 < len(w.Snakes) {
+			next = w.Snakes[i+1].ID
+		}
+	}
+	t.id = next
+	t.spectator.Follow(next)
+}
+
+func (t *tui) update() error {
+	if t.spectator != nil {
+		w, path, err := t.spectator.Latest()
+		if err == netplay.ErrNoSnapshot {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		t.world, t.path = w, path
+		return nil
+	}
+	// The bots get going once the player does.
+	if p := t.world.Snake(t.id); p != nil && p.Dir != sim.DirNone {
+		for _, s := range t.world.Snakes {
+			if s.ID != t.id && s.AI && !s.Dead && s.Dir == sim.DirNone {
+				s.Dir = sim.DirUp
+			}
+		}
+	}
+	t.world.Update()
+	return nil
+}
+
+// draw returns the next picture, from the top left corner of the terminal.
+// Every character is two cells, the top one in the colour of the character
+// and the bottom one in that of its background.
+func (t *tui) draw() []byte {
+	var b bytes.Buffer
+	b.WriteString("\x1b[H")
+	w := t.world
+	if w == nil {
+		b.WriteString("Waiting for the game\x1b[K")
+		return b.Bytes()
+	}
+
+	th := t.theme
+	cells := make([]theme.Color, w.Width*(w.Height+1))
+	set := func(p sim.Position, c theme.Color) {
+		if p.X >= 0 && p.Y >= 0 && p.X < w.Width && p.Y < w.Height {
+			cells[p.Y*w.Width+p.X] = c
+		}
+	}
+	for i := range cells {
+		cells[i] = th.Background
+	}
+	for _, p := range t.path {
+		set(p, th.Path)
+	}
+	for _, s := range w.Snakes {
+		c := th.Others
+		if s.ID == t.id {
+			c = th.Player
+		}
+		for i, p := range s.Body {
+			set(p, c.Segment(i, len(s.Body)))
+		}
+	}
+	for _, p := range w.Walls {
+		set(p, th.Walls)
+	}
+	for _, f := range w.Food {
+		set(f.Pos, th.Food[f.Kind])
+	}
+	for i, p := range w.Portals {
+		set(p.A, th.Portals[i%len(th.Portals)])
+		set(p.B, th.Portals[i%len(th.Portals)])
+	}
+	for _, p := range w.PowerUps {
+		set(p.Pos, th.PowerUps[p.Kind])
+	}
+	set(w.Apple, th.Apple)
+
+	var fg, bg theme.Color
+	for y := 0; y < w.Height; y += 2 {
+		for x := 0; x < w.Width; x++ {
+			top, bottom := cells[y*w.Width+x], cells[(y+1)*w.Width+x]
+			if y+1 == w.Height {
+				bottom = th.Background
+			}
+			if x == 0 || top != fg {
+				fg = top
+				fmt.Fprintf(&b, "\x1b[38;2;%d;%d;%dm", fg.R, fg.G, fg.B)
+			}
+			if x == 0 || bottom != bg {
+				bg = bottom
+				fmt.Fprintf(&b, "\x1b[48;2;%d;%d;%dm", bg.R, bg.G, bg.B)
+			}
+			b.WriteString("▀")
+		}
+		b.WriteString("\x1b[0m\x1b[K\n")
+	}
+	b.WriteString(t.status() + "\x1b[K\n")
+	b.WriteString(t.help() + "\x1b[K")
+	return b.Bytes()
+}
+
+// status is the line under the board: the numbers of the followed snake
+// and how the game is going.
+func (t *tui) status() string {
+	w := t.world
+	s := w.Snake(t.id)
+	if s == nil {
+		return w.Mode
+	}
+	line := fmt.Sprintf("%s  Score %d  Best %d  Level %d  Length %d", w.Mode, s.Score, s.Best, s.Level, len(s.Body))
+	if s.AI {
+		line += "  AI"
+	}
+	if left := w.TimeLeft(); left >= 0 {
+		line += fmt.Sprintf("  %ds left", (left+netplay.TicksPerSecond-1)/netplay.TicksPerSecond)
+	}
+	if w.Mode == sim.ModeSprint {
+		line += fmt.Sprintf("  %d/%d in %ds", len(s.Body), w.SprintLength, w.Clock/netplay.TicksPerSecond)
+	}
+	switch winner := w.Snake(w.Winner); {
+	case !w.Over:
+	case winner == nil:
+		line += "  Game over"
+	case winner.ID == t.id && t.spectator == nil:
+		line += "  You win"
+	default:
+		line += "  " + winner.Name + " wins"
+	}
+	if s.Dead && !w.Over {
+		line += "  Out"
+	}
+	return line
+}
+
+func (t *tui) help() string {
+	if t.spectator != nil {
+		return "Tab follows the next snake, q quits"
+	}
+	return "Arrows, WASD or HJKL steer, space hands over to the AI, r starts over, q quits"
+}
+
+// terminalSize returns the columns and rows of the terminal, 80 by 24 if it
+// can't tell.
+func terminalSize() (cols, rows int) {
+	out, err := stty("size")
+	if f := strings.Fields(out); err == nil && len(f) == 2 {
+		rows, _ = strconv.Atoi(f[0])
+		cols, _ = strconv.Atoi(f[1])
+	}
+	if cols <= 0 || rows <= 0 {
+		return 80, 24
+	}
+	return cols, rows
+}
+
+// rawTerminal hands every key to stdin as it is pressed, without showing
+// it, and returns what puts the terminal back the way it was.
+func rawTerminal() (restore func(), err error) {
+	state, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("stdin is not a terminal: %v", err)
+	}
+	if _, err := stty("-icanon", "-echo", "min", "1"); err != nil {
+		return nil, err
+	}
+	return func() { stty(strings.TrimSpace(state)) }, nil
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
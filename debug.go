@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"runtime"
+	"strings"
+
+	"ebiten/Snake/settings"
+	"ebiten/Snake/sim"
+
+	"github.com/hajimehoshi/ebiten"
+	"github.com/hajimehoshi/ebiten/ebitenutil"
+)
+
+// inputLog is how many of the player's last inputs the overlay lists.
+const inputLog = 8
+
+var (
+	debugGrid    = color.RGBA{0x20, 0x20, 0x20, 0xff}
+	debugPath    = color.RGBA{0xff, 0x00, 0xff, 0x60}
+	debugVisited = color.RGBA{0xff, 0xff, 0x00, 0xa0}
+)
+
+// loggedInput is an input the player gave and the frame it came in.
+type loggedInput struct {
+	frame int
+	in    sim.Input
+}
+
+func (l loggedInput) String() string {
+	name := "?"
+	switch {
+	case l.in.Reset:
+		name = "reset"
+	case l.in.ToggleAI:
+		name = "ai"
+	default:
+		name = [...]string{"-", "L", "R", "D", "U"}[l.in.Dir]
+	}
+	return fmt.Sprintf("%s@%d", name, l.frame)
+}
+
+// logInput remembers the player's last few inputs for the overlay.
+func (g *Game) logInput(in sim.Input) {
+	if in == (sim.Input{}) {
+		return
+	}
+	g.inputs = append(g.inputs, loggedInput{g.frames, in})
+	if len(g.inputs) > inputLog {
+		g.inputs = g.inputs[len(g.inputs)-inputLog:]
+	}
+}
+
+// updateDebug toggles the overlay, on F3 by default, and counts frames. The memory
+// numbers are read once a second, reading them stops the world.
+func (g *Game) updateDebug() {
+	if g.pressed(settings.ActionOverlay) {
+		g.debug = !g.debug
+	}
+	if g.debug && (g.frames%ebiten.MaxTPS() == 0 || g.mem.Sys == 0) {
+		runtime.ReadMemStats(&g.mem)
+	}
+	g.frames++
+}
+
+// drawGrid lines out the cells under everything else on the board, in the
+// colour of the theme if it has one for them.
+func (g *Game) drawGrid(board *ebiten.Image) {
+	var c color.Color = debugGrid
+	if g.theme.Grid.Visible() {
+		c = g.theme.Grid
+	}
+	w, h := float64(g.world.Width*gridSize), float64(g.world.Height*gridSize)
+	for x := 1; x < g.world.Width; x++ {
+		ebitenutil.DrawLine(board, float64(x*gridSize), 0, float64(x*gridSize), h, c)
+	}
+	for y := 1; y < g.world.Height; y++ {
+		ebitenutil.DrawLine(board, 0, float64(y*gridSize), w, float64(y*gridSize), c)
+	}
+}
+
+// drawDebug is the developer overlay: what the AI snakes are up to, a line
+// to the apple and the numbers behind the game.
+func (g *Game) drawDebug(board *ebiten.Image) {
+	for _, s := range g.world.Snakes {
+		if s.Dead || len(s.Body) == 0 {
+			continue
+		}
+		path, visited := g.world.PlanSearch(s, 64)
+		for _, v := range path {
+			ebitenutil.DrawRect(board, float64(v.X*gridSize), float64(v.Y*gridSize), gridSize, gridSize, debugPath)
+		}
+		for _, v := range visited {
+			ebitenutil.DrawRect(board, float64(v.X*gridSize+3), float64(v.Y*gridSize+3), gridSize-6, gridSize-6, debugVisited)
+		}
+	}
+
+	lines := []string{
+		fmt.Sprintf("FPS %0.1f  TPS %0.1f", ebiten.CurrentFPS(), ebiten.CurrentTPS()),
+		fmt.Sprintf("Tick %d  Clock %d", g.world.Timer, g.world.Clock),
+		fmt.Sprintf("Heap %.1f MB  Sys %.1f MB  GC %d", float64(g.mem.HeapAlloc)/(1<<20), float64(g.mem.Sys)/(1<<20), g.mem.NumGC),
+	}
+	if p := g.player(); p != nil && len(p.Body) > 0 {
+		head, apple := p.Head(), g.world.Apple
+		lines = append(lines,
+			fmt.Sprintf("MoveTime %d, %d now", p.MoveTime, g.world.MoveTime(p)),
+			fmt.Sprintf("Len %d", g.world.Distance(head, apple)*gridSize))
+
+		// The line to the apple goes through a portal when that is shorter.
+		from := head
+		if via := g.world.Waypoint(head, apple); via != apple {
+			ebitenutil.DrawLine(board, float64(head.X*gridSize), float64(head.Y*gridSize), float64(via.X*gridSize), float64(via.Y*gridSize), debugLine)
+			from = g.world.Partner(via)
+		}
+		ebitenutil.DrawLine(board, float64(from.X*gridSize), float64(from.Y*gridSize), float64(apple.X*gridSize), float64(apple.Y*gridSize), debugLine)
+	}
+
+	var inputs []string
+	for _, l := range g.inputs {
+		inputs = append(inputs, l.String())
+	}
+	queue := "Input " + strings.Join(inputs, " ")
+	switch {
+	case g.client != nil:
+		queue += fmt.Sprintf("  unacknowledged %d", g.client.Pending())
+	case g.peer != nil:
+		queue += fmt.Sprintf("  delay %d", g.peer.Delay)
+	}
+	lines = append(lines, queue)
+	ebitenutil.DebugPrint(board, strings.Join(lines, "\n"))
+}
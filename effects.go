@@ -0,0 +1,156 @@
+package main
+
+import (
+	"image/color"
+	"math"
+	"math/rand"
+
+	"ebiten/Snake/sim"
+
+	"github.com/hajimehoshi/ebiten"
+	"github.com/hajimehoshi/ebiten/ebitenutil"
+)
+
+const (
+	// maxParticles keeps a royale full of crashes from slowing the game.
+	maxParticles = 2000
+
+	// shakeFrames is how long the board shakes after the player crashes,
+	// shakeSize how many pixels it moves at most.
+	shakeFrames = 12
+	shakeSize   = 4
+
+	// particleDrag is how much of its speed a particle keeps every frame.
+	particleDrag = 0.92
+)
+
+// particle is a speck flying off from something that happened on the
+// board, in pixels on the board.
+type particle struct {
+	x, y   float64
+	vx, vy float64
+	size   float64
+	age    int
+	life   int
+	c      color.Color
+}
+
+// lastSnake is what a snake was like last frame, to tell what happened to
+// it since.
+type lastSnake struct {
+	body    []sim.Position
+	score   int
+	crashes int
+	dead    bool
+}
+
+// updateEffects sets off a burst where an apple was eaten and scatters the
+// body of a snake that crashed, shaking the board if it was the player's,
+// and moves the particles on.
+func (g *Game) updateEffects() {
+	particles := g.particles[:0]
+	for _, p := range g.particles {
+		p.age++
+		p.x, p.y = p.x+p.vx, p.y+p.vy
+		p.vx, p.vy = p.vx*particleDrag, p.vy*particleDrag
+		if p.age < p.life {
+			particles = append(particles, p)
+		}
+	}
+	g.particles = particles
+	if g.shake > 0 {
+		g.shake--
+	}
+
+	if g.world == nil {
+		g.lastSnakes = nil
+		return
+	}
+	last := g.lastSnakes
+	g.lastSnakes = map[int]lastSnake{}
+	ate := false
+	for _, s := range g.world.Snakes {
+		g.lastSnakes[s.ID] = lastSnake{append([]sim.Position(nil), s.Body...), s.Score, s.Crashes, s.Dead}
+		l, ok := last[s.ID]
+		if !ok {
+			continue
+		}
+		ate = ate || s.Score > l.score
+		switch {
+		case s.Crashes > l.crashes && g.world.Practice && !s.AI && !s.Dead:
+			// Practice stopped it short, there is nothing to scatter.
+			g.jolt(s)
+		case s.Crashes > l.crashes, s.Dead && !l.dead:
+			g.jolt(s)
+			g.explode(s, l.body)
+		}
+	}
+	if last != nil && g.lastApple != g.world.Apple && ate {
+		g.burst(g.lastApple, g.theme.Apple)
+	}
+	g.lastApple = g.world.Apple
+}
+
+// burst sends sparks flying every way from the middle of a cell.
+func (g *Game) burst(at sim.Position, c color.Color) {
+	if !g.prefs.Particles {
+		return
+	}
+	x, y := float64(at.X*gridSize+gridSize/2), float64(at.Y*gridSize+gridSize/2)
+	for i := 0; i < 16; i++ {
+		a, v := rand.Float64()*2*math.Pi, 1+rand.Float64()*2
+		g.addParticle(particle{x: x, y: y, vx: v * math.Cos(a), vy: v * math.Sin(a), size: 3, life: 20 + rand.Intn(10), c: c})
+	}
+}
+
+// jolt shakes the board if the snake that crashed is the player's.
+func (g *Game) jolt(s *sim.Snake) {
+	if s == g.player() && g.prefs.ScreenShake {
+		g.shake = shakeFrames
+	}
+}
+
+// explode scatters the body a snake had before it crashed, segment by
+// segment.
+func (g *Game) explode(s *sim.Snake, body []sim.Position) {
+	if !g.prefs.Particles {
+		return
+	}
+	colors := g.theme.Others
+	if s == g.player() {
+		colors = g.theme.Player
+	}
+	for i, p := range body {
+		a, v := rand.Float64()*2*math.Pi, 0.5+rand.Float64()*2.5
+		g.addParticle(particle{
+			x: float64(p.X * gridSize), y: float64(p.Y * gridSize),
+			vx: v * math.Cos(a), vy: v * math.Sin(a),
+			size: gridSize - 2, life: 30 + rand.Intn(20), c: colors.Segment(i, len(body)),
+		})
+	}
+}
+
+func (g *Game) addParticle(p particle) {
+	if len(g.particles) < maxParticles {
+		g.particles = append(g.particles, p)
+	}
+}
+
+// drawParticles draws the particles over the board, fading as they age.
+func (g *Game) drawParticles(board *ebiten.Image) {
+	for _, p := range g.particles {
+		c := color.NRGBAModel.Convert(p.c).(color.NRGBA)
+		c.A = uint8(float64(c.A) * float64(p.life-p.age) / float64(p.life))
+		ebitenutil.DrawRect(board, p.x, p.y, p.size, p.size, c)
+	}
+}
+
+// shakeOffset returns how far the board is shaken off its place this
+// frame, less and less as the shake wears off.
+func (g *Game) shakeOffset() (float64, float64) {
+	if g.shake == 0 {
+		return 0, 0
+	}
+	size := shakeSize * float64(g.shake) / shakeFrames
+	return (rand.Float64()*2 - 1) * size, (rand.Float64()*2 - 1) * size
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+
+	"ebiten/Snake/settings"
+	"ebiten/Snake/sim"
+
+	"github.com/hajimehoshi/ebiten"
+	"github.com/hajimehoshi/ebiten/inpututil"
+)
+
+// padDeadzone is how far a stick has to be pushed before it counts.
+const padDeadzone = 0.5
+
+// gamepad is a connected gamepad. stick is the way its left stick was
+// pushed last frame, so holding it turns only once.
+type gamepad struct {
+	id    int
+	stick int
+}
+
+// updateGamepads hands gamepads that were just plugged in to the first
+// player without one and frees those that were pulled out.
+func (g *Game) updateGamepads() {
+	for i, p := range g.pads {
+		if p != nil && inpututil.IsGamepadJustDisconnected(p.id) {
+			g.pads[i] = nil
+			g.notify(fmt.Sprintf("Player %d's gamepad is gone", i+1))
+		}
+	}
+	for _, id := range inpututil.JustConnectedGamepadIDs() {
+		i := 0
+		for i < len(g.pads) && g.pads[i] != nil {
+			i++
+		}
+		if i == len(g.pads) {
+			g.pads = append(g.pads, nil)
+		}
+		g.pads[i] = &gamepad{id: id}
+		log.Printf("gamepad %d: %s", id, ebiten.GamepadName(id))
+		g.notify(fmt.Sprintf("Gamepad for player %d", i+1))
+	}
+	for _, p := range g.pads {
+		if p != nil {
+			p.stick = padStick(p.id)
+		}
+	}
+}
+
+// notify shows a message in the HUD for a few seconds.
+func (g *Game) notify(msg string) {
+	g.notice, g.noticeUntil = msg, g.frames+3*ebiten.MaxTPS()
+}
+
+// pad returns the gamepad of a local player, counting from 0, or nil.
+func (g *Game) pad(player int) *gamepad {
+	if player < len(g.pads) {
+		return g.pads[player]
+	}
+	return nil
+}
+
+// padPressed reports whether a button bound to action was pressed on the
+// gamepad this frame.
+func (g *Game) padPressed(p *gamepad, action string) bool {
+	if p == nil {
+		return false
+	}
+	for _, b := range g.prefs.Buttons[action] {
+		if inpututil.IsGamepadButtonJustPressed(p.id, ebiten.GamepadButton(b)) {
+			return true
+		}
+	}
+	return false
+}
+
+// anyPadPressed reports whether a button bound to action was pressed on
+// any gamepad this frame.
+func (g *Game) anyPadPressed(action string) bool {
+	for _, p := range g.pads {
+		if g.padPressed(p, action) {
+			return true
+		}
+	}
+	return false
+}
+
+// menu reports whether key or a gamepad button bound to action was pressed,
+// for the screens that aren't the game.
+func (g *Game) menu(key ebiten.Key, action string) bool {
+	return inpututil.IsKeyJustPressed(key) || g.anyPadPressed(action)
+}
+
+// padStick returns the way the left stick of a gamepad is pushed, the axis
+// it is pushed along furthest deciding.
+func padStick(id int) int {
+	if ebiten.GamepadAxisNum(id) < 2 {
+		return sim.DirNone
+	}
+	x, y := ebiten.GamepadAxis(id, 0), ebiten.GamepadAxis(id, 1)
+	switch {
+	case math.Max(math.Abs(x), math.Abs(y)) < padDeadzone:
+		return sim.DirNone
+	case math.Abs(x) > math.Abs(y) && x < 0:
+		return sim.DirLeft
+	case math.Abs(x) > math.Abs(y):
+		return sim.DirRight
+	case y < 0:
+		return sim.DirUp
+	}
+	return sim.DirDown
+}
+
+// playerPressed reports whether a local player pressed action, player 0
+// on the keyboard or a gamepad and the others on their gamepads.
+func (g *Game) playerPressed(player int, action string) bool {
+	if g.keyScreen != nil {
+		return false
+	}
+	return player == 0 && g.keyPressed(action) || g.padPressed(g.pad(player), action)
+}
+
+// stickTurn returns the way a player's stick was just pushed.
+func (g *Game) stickTurn(player int) int {
+	p := g.pad(player)
+	if p == nil || g.keyScreen != nil {
+		return sim.DirNone
+	}
+	if dir := padStick(p.id); dir != p.stick {
+		return dir
+	}
+	return sim.DirNone
+}
+
+// readInput turns a local player's presses this frame into their input.
+func (g *Game) readInput(player int) sim.Input {
+	var in sim.Input
+	if g.playerPressed(player, settings.ActionLeft) {
+		in.Dir = sim.DirLeft
+	} else if g.playerPressed(player, settings.ActionRight) {
+		in.Dir = sim.DirRight
+	} else if g.playerPressed(player, settings.ActionDown) {
+		in.Dir = sim.DirDown
+	} else if g.playerPressed(player, settings.ActionUp) {
+		in.Dir = sim.DirUp
+	} else if dir := g.stickTurn(player); dir != sim.DirNone {
+		in.Dir = dir
+	} else if player == 0 && g.touchDir != sim.DirNone {
+		in.Dir = g.touchDir
+	} else if dir := g.mouseTurn(player); dir != sim.DirNone {
+		in.Dir = dir
+	} else if player == 0 && g.tapped && g.world != nil && g.world.Over {
+		// There is no Escape on a phone.
+		in.Reset = true
+	} else if g.playerPressed(player, settings.ActionReset) {
+		in.Reset = true
+	} else if g.playerPressed(player, settings.ActionAI) {
+		in.ToggleAI = true
+	}
+	return in
+}
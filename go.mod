@@ -2,4 +2,9 @@ module ebiten/Snake
 
 go 1.12
 
-require github.com/hajimehoshi/ebiten v1.11.4
+require (
+	github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0
+	github.com/gorilla/websocket v1.4.2
+	github.com/hajimehoshi/ebiten v1.11.4
+	golang.org/x/image v0.0.0-20191009234506-e7c1f5e7dbb8
+)
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log"
+	"strings"
+
+	"ebiten/Snake/sim"
+
+	"github.com/golang/freetype/truetype"
+	"github.com/hajimehoshi/ebiten"
+	"github.com/hajimehoshi/ebiten/ebitenutil"
+	"github.com/hajimehoshi/ebiten/text"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/gomedium"
+	"golang.org/x/image/font/gofont/gomono"
+)
+
+// hudHeight is the band above the board the HUD is drawn in.
+const hudHeight = 40
+
+// popupFrames is how long a score popup stays up.
+const popupFrames = 40
+
+var (
+	hudFace   font.Face // the HUD
+	textFace  font.Face // messages and tables on the board, monospaced
+	popupFace font.Face // score popups
+)
+
+var (
+	textShadow = color.RGBA{0x00, 0x00, 0x00, 0xc0}
+	debugLine  = color.RGBA{0x00, 0x00, 0xff, 0xff}
+)
+
+func loadFonts() {
+	load := func(ttf []byte, size float64) font.Face {
+		f, err := truetype.Parse(ttf)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return truetype.NewFace(f, &truetype.Options{Size: size, DPI: 72, Hinting: font.HintingFull})
+	}
+	hudFace = load(gomedium.TTF, 15)
+	textFace = load(gomono.TTF, 12)
+	popupFace = load(gobold.TTF, 14)
+}
+
+// drawText draws s with its first line's top at y, which DebugPrintAt users
+// are used to, rather than at the baseline like text.Draw.
+func drawText(dst *ebiten.Image, s string, face font.Face, x, y int, clr color.Color) {
+	y += face.Metrics().Ascent.Ceil()
+	text.Draw(dst, s, face, x+1, y+1, textShadow)
+	text.Draw(dst, s, face, x, y, clr)
+}
+
+// popup is a score floating up from where it was made.
+type popup struct {
+	pos  sim.Position
+	text string
+	age  int
+}
+
+// updatePopups adds a popup when the player scores and ages the others.
+func (g *Game) updatePopups() {
+	popups := g.popups[:0]
+	for _, p := range g.popups {
+		p.age++
+		if p.age < popupFrames {
+			popups = append(popups, p)
+		}
+	}
+	g.popups = popups
+
+	if g.world == nil {
+		g.lastScore = 0
+		return
+	}
+	p := g.player()
+	if p == nil || len(p.Body) == 0 {
+		g.lastScore = 0
+		return
+	}
+	if d := p.Score - g.lastScore; d > 0 {
+		g.popups = append(g.popups, popup{pos: p.Head(), text: fmt.Sprintf("+%d", d)})
+	}
+	g.lastScore = p.Score
+}
+
+func (g *Game) drawPopups(board *ebiten.Image) {
+	for _, p := range g.popups {
+		c := g.theme.HUD.Popup
+		c.A = uint8(0xff * (popupFrames - p.age) / popupFrames)
+		x := p.pos.X*gridSize + gridSize
+		y := p.pos.Y*gridSize - p.age/2
+		text.Draw(board, p.text, popupFace, x, y, c)
+	}
+}
+
+// speed is how many cells a second the snake goes at the moment.
+func (g *Game) speed(s *sim.Snake) float64 {
+	return float64(ebiten.MaxTPS()) / float64(g.world.MoveTime(s))
+}
+
+// drawHUD fills the band above the board: the player's numbers on top and
+// whatever the game has to say below.
+func (g *Game) drawHUD(screen *ebiten.Image) {
+	ebitenutil.DrawRect(screen, 0, 0, screenWidth, hudHeight, g.theme.HUD.Back)
+	if g.world == nil {
+		return
+	}
+	p := g.player()
+	mode := g.world.Mode
+	if g.daily != "" {
+		mode = "daily " + g.daily
+	}
+	if p != nil && p.AI {
+		mode += ", AI"
+	}
+	if g.world.Practice {
+		mode += ", practice"
+	}
+	mode += g.stepStatus()
+	w := font.MeasureString(hudFace, mode).Ceil()
+	drawText(screen, mode, hudFace, screenWidth-w-8, 2, g.theme.HUD.Dim)
+	if p == nil {
+		drawText(screen, g.hudMessage(nil), hudFace, 8, 20, g.theme.HUD.Dim)
+		return
+	}
+
+	stats := fmt.Sprintf("Score %d   Best %d   Level %d   Length %d   Speed %.0f/s", p.Score, p.Best, p.Level, len(p.Body), g.speed(p))
+	drawText(screen, stats, hudFace, 8, 2, g.theme.HUD.Text)
+	drawText(screen, g.hudMessage(p), hudFace, 8, 20, g.theme.HUD.Dim)
+}
+
+// hudMessage is the second line of the HUD.
+func (g *Game) hudMessage(p *sim.Snake) string {
+	switch {
+	case g.notice != "" && g.frames < g.noticeUntil:
+		return g.notice
+	case p == nil && g.spectator != nil:
+		return "Spectating, press Tab to follow a snake"
+	case p == nil:
+		return ""
+	case g.world.Over:
+		return ""
+	case p.Dead:
+		return fmt.Sprintf("You are out, %d snakes left", g.world.Alive())
+	case g.spectator != nil:
+		return fmt.Sprintf("Spectating %s (%d), Tab for next", p.Name, p.ID)
+	case p.Dir == sim.DirNone && g.world.Practice && len(p.Body) > 1:
+		return "Stopped short of a crash, turn to go on"
+	case p.Dir == sim.DirNone && g.playback == nil:
+		return "Press up/down/left/right to start"
+	}
+	return strings.TrimSpace(g.challengeStatus(p) + "   " + g.effects(p))
+}
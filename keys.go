@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"ebiten/Snake/settings"
+	"ebiten/Snake/theme"
+
+	"github.com/hajimehoshi/ebiten"
+	"github.com/hajimehoshi/ebiten/inpututil"
+)
+
+// keysByName finds a key by the name settings files use for it, which is
+// the one ebiten gives it.
+var keysByName = map[string]ebiten.Key{}
+
+func init() {
+	for k := ebiten.Key(0); k <= ebiten.KeyMax; k++ {
+		if name := k.String(); name != "" {
+			keysByName[name] = k
+		}
+	}
+}
+
+// loadSettings reads the player's settings and binds the keys they name,
+// and reads the themes to pick theirs from.
+func (g *Game) loadSettings() {
+	var err error
+	if g.prefs, err = settings.Load(settings.Path()); err != nil {
+		log.Println(err)
+	}
+	for _, c := range g.prefs.Conflicts() {
+		log.Println(c)
+	}
+	g.bindKeys()
+	if g.themes, err = theme.Load(theme.Path()); err != nil {
+		log.Println(err)
+	}
+	g.theme = theme.Find(g.themes, g.prefs.Theme)
+	g.applyPractice()
+}
+
+// bindKeys looks up the keys of every action.
+func (g *Game) bindKeys() {
+	g.bindings = map[string][]ebiten.Key{}
+	for action, names := range g.prefs.Keys {
+		for _, name := range names {
+			k, ok := keysByName[name]
+			if !ok {
+				log.Printf("unknown key %q for %s", name, action)
+				continue
+			}
+			g.bindings[action] = append(g.bindings[action], k)
+		}
+	}
+}
+
+// keyPressed reports whether a key bound to action was pressed this frame.
+func (g *Game) keyPressed(action string) bool {
+	for _, k := range g.bindings[action] {
+		if inpututil.IsKeyJustPressed(k) {
+			return true
+		}
+	}
+	return false
+}
+
+// pressed reports whether action was pressed this frame, on the keyboard or
+// any gamepad. Nothing is while the keys are being rebound.
+func (g *Game) pressed(action string) bool {
+	return g.keyScreen == nil && (g.keyPressed(action) || g.anyPadPressed(action))
+}
+
+// keyScreen is where the keys are rebound and the theme picked. F1 opens
+// and closes it.
+type keyScreen struct {
+	sel       int    // the action picked, or the option after the last one
+	capturing bool   // waiting for a key to bind to it
+	msg       string // what went wrong last
+	closing   bool   // closed this frame, gone the next
+}
+
+// updateKeyScreen opens and closes the key screen and, while it is open,
+// rebinds the keys:
+//
+//	Up/Down    pick an action
+//	Enter      bind one more key to it, the next one pressed
+//	Backspace  unbind its last key
+//	F5         bind every action to its default keys
+//
+// Below the actions Left, Right or Enter change the options, there and then.
+//
+// The settings are saved when it closes. It reports whether the screen is
+// open, which it still is on the frame it closes, so the Escape that closes
+// it doesn't start the game over as well.
+func (g *Game) updateKeyScreen() bool {
+	ks := g.keyScreen
+	if ks != nil && ks.closing {
+		g.keyScreen, ks = nil, nil
+	}
+	if ks == nil {
+		if inpututil.IsKeyJustPressed(ebiten.KeyF1) {
+			g.keyScreen = &keyScreen{}
+			return true
+		}
+		return false
+	}
+
+	if ks.capturing {
+		ks.capturing = false
+		for k := ebiten.Key(0); k <= ebiten.KeyMax; k++ {
+			if !inpututil.IsKeyJustPressed(k) {
+				continue
+			}
+			ks.msg = ""
+			if k == ebiten.KeyF1 {
+				// F1 always gets out, so it can't be bound.
+				return true
+			}
+			if err := g.prefs.Bind(settings.Actions[ks.sel], k.String()); err != nil {
+				ks.msg = err.Error()
+			}
+			g.bindKeys()
+			return true
+		}
+		ks.capturing = true
+		return true
+	}
+
+	switch {
+	case inpututil.IsKeyJustPressed(ebiten.KeyF1), g.menu(ebiten.KeyEscape, settings.ButtonBack):
+		ks.closing = true
+		if err := g.prefs.Save(); err != nil {
+			log.Println(err)
+		}
+		return true
+	case g.menu(ebiten.KeyUp, settings.ActionUp):
+		ks.sel = (ks.sel + keyScreenRows - 1) % keyScreenRows
+	case g.menu(ebiten.KeyDown, settings.ActionDown):
+		ks.sel = (ks.sel + 1) % keyScreenRows
+	case inpututil.IsKeyJustPressed(ebiten.KeyF5):
+		g.prefs.Reset()
+		g.bindKeys()
+	case ks.sel >= len(settings.Actions):
+		o := options[ks.sel-len(settings.Actions)]
+		switch {
+		case g.menu(ebiten.KeyLeft, settings.ActionLeft):
+			o.change(g, -1)
+		case g.menu(ebiten.KeyRight, settings.ActionRight), g.menu(ebiten.KeyEnter, settings.ButtonConfirm):
+			o.change(g, 1)
+		}
+		// Practice goes on or off in the game there and then.
+		g.applyPractice()
+	case inpututil.IsKeyJustPressed(ebiten.KeyEnter):
+		ks.capturing, ks.msg = true, ""
+	case inpututil.IsKeyJustPressed(ebiten.KeyBackspace):
+		g.prefs.Unbind(settings.Actions[ks.sel])
+		g.bindKeys()
+	}
+	return true
+}
+
+// option is a setting the key screen changes below the actions: value is
+// what it is set to, change moves it on by step.
+type option struct {
+	name   string
+	value  func(g *Game) string
+	change func(g *Game, step int)
+}
+
+var options = []option{
+	{"theme", func(g *Game) string { return g.theme.Name }, (*Game).switchTheme},
+	toggle("outlines", func(s *settings.Settings) *bool { return &s.Outlines }),
+	toggle("shapes", func(s *settings.Settings) *bool { return &s.FoodShapes }),
+	{"speed", func(g *Game) string { return fmt.Sprintf("%d%%", g.speedAssist()) }, (*Game).switchSpeed},
+	toggle("cues", func(s *settings.Settings) *bool { return &s.AppleCues }),
+	toggle("practice", func(s *settings.Settings) *bool { return &s.Practice }),
+	toggle("particles", func(s *settings.Settings) *bool { return &s.Particles }),
+	toggle("shake", func(s *settings.Settings) *bool { return &s.ScreenShake }),
+}
+
+// keyScreenRows is how many rows there are to pick from: every action and
+// every option.
+var keyScreenRows = len(settings.Actions) + len(options)
+
+// toggle is an option that is on or off, the setting field points at.
+func toggle(name string, field func(*settings.Settings) *bool) option {
+	value := func(g *Game) string {
+		if *field(g.prefs) {
+			return "on"
+		}
+		return "off"
+	}
+	change := func(g *Game, _ int) {
+		on := field(g.prefs)
+		*on = !*on
+	}
+	return option{name, value, change}
+}
+
+// switchTheme moves step themes on and draws the game in that one.
+func (g *Game) switchTheme(step int) {
+	g.theme = theme.Next(g.themes, g.theme.Name, step)
+	g.prefs.Theme = g.theme.Name
+}
+
+func (g *Game) drawKeyScreen(screen *ebiten.Image) {
+	ks := g.keyScreen
+	var b strings.Builder
+	b.WriteString("Keys\n\n")
+	for i, action := range settings.Actions {
+		mark := " "
+		if i == ks.sel {
+			mark = ">"
+		}
+		keys := strings.Join(g.prefs.Keys[action], ", ")
+		if i == ks.sel && ks.capturing {
+			keys += ", press a key"
+		}
+		fmt.Fprintf(&b, "%s %-10s %s\n", mark, action, keys)
+	}
+	b.WriteString("\n")
+	for i, o := range options {
+		mark := " "
+		if len(settings.Actions)+i == ks.sel {
+			mark = ">"
+		}
+		fmt.Fprintf(&b, "%s %-10s < %s >\n", mark, o.name, o.value(g))
+	}
+	b.WriteString("\n")
+	if ks.msg != "" {
+		b.WriteString(ks.msg + "\n\n")
+	}
+	b.WriteString("Up/Down pick, Enter adds a key, Backspace removes one,\nLeft/Right change options, F5 puts back the default keys,\nEscape or F1 saves and closes")
+	drawText(screen, b.String(), textFace, 20, 20, g.theme.HUD.Text)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"ebiten/Snake/netplay"
+	"ebiten/Snake/settings"
+	"ebiten/Snake/sim"
+
+	"github.com/hajimehoshi/ebiten"
+	"github.com/hajimehoshi/ebiten/inpututil"
+)
+
+// lobby is the scene shown while connected to a server but not playing.
+type lobby struct {
+	cursor   int
+	settings netplay.Settings
+	ready    bool
+}
+
+// Choices for a new room. The first of each is picked by default.
+var (
+	lobbySizes   = [][2]int{{64, 48}, {48, 36}, {32, 24}}
+	lobbyWalls   = []string{netplay.WallsSolid, netplay.WallsWrap}
+	lobbySpeeds  = []string{"normal", "slow", "fast"}
+	lobbyPlayers = []int{4, 2, 3, 5, 6, 7, 8, 1}
+	lobbyModes   = []string{sim.ModeClassic, sim.ModeRoyale}
+)
+
+func newLobby() *lobby {
+	return &lobby{settings: netplay.DefaultSettings}
+}
+
+func (l *lobby) cycleSize() {
+	i := 0
+	for j, sz := range lobbySizes {
+		if sz[0] == l.settings.Width && sz[1] == l.settings.Height {
+			i = (j + 1) % len(lobbySizes)
+		}
+	}
+	l.settings.Width, l.settings.Height = lobbySizes[i][0], lobbySizes[i][1]
+}
+
+func nextString(list []string, cur string) string {
+	for i, v := range list {
+		if v == cur {
+			return list[(i+1)%len(list)]
+		}
+	}
+	return list[0]
+}
+
+func nextInt(list []int, cur int) int {
+	for i, v := range list {
+		if v == cur {
+			return list[(i+1)%len(list)]
+		}
+	}
+	return list[0]
+}
+
+func (g *Game) updateLobby() error {
+	l, c := g.lobby, g.client
+	r := c.Room()
+	if r != nil {
+		switch {
+		case g.menu(ebiten.KeyEnter, settings.ButtonConfirm):
+			l.ready = !l.ready
+			return c.Ready(l.ready)
+		case g.menu(ebiten.KeyEscape, settings.ButtonBack):
+			l.ready = false
+			return c.Leave()
+		}
+		return nil
+	}
+
+	l.ready = false
+	rooms := c.Rooms()
+	switch {
+	case g.menu(ebiten.KeyUp, settings.ActionUp):
+		if l.cursor > 0 {
+			l.cursor--
+		}
+	case g.menu(ebiten.KeyDown, settings.ActionDown):
+		if l.cursor < len(rooms)-1 {
+			l.cursor++
+		}
+	case g.menu(ebiten.KeyEnter, settings.ButtonConfirm):
+		if l.cursor < len(rooms) {
+			return c.Join(rooms[l.cursor].ID)
+		}
+	case inpututil.IsKeyJustPressed(ebiten.KeyN):
+		return c.Create("", l.settings)
+	case inpututil.IsKeyJustPressed(ebiten.KeyR):
+		return c.List()
+	case inpututil.IsKeyJustPressed(ebiten.Key1):
+		l.cycleSize()
+	case inpututil.IsKeyJustPressed(ebiten.Key2):
+		l.settings.Walls = nextString(lobbyWalls, l.settings.Walls)
+	case inpututil.IsKeyJustPressed(ebiten.Key3):
+		l.settings.Speed = nextString(lobbySpeeds, l.settings.Speed)
+	case inpututil.IsKeyJustPressed(ebiten.Key4):
+		l.settings.MaxPlayers = nextInt(lobbyPlayers, l.settings.MaxPlayers)
+	case inpututil.IsKeyJustPressed(ebiten.Key5):
+		l.settings.Mode = nextString(lobbyModes, l.settings.Mode)
+	case inpututil.IsKeyJustPressed(ebiten.Key6):
+		l.settings.PowerUps = !l.settings.PowerUps
+	case inpututil.IsKeyJustPressed(ebiten.Key8):
+		l.settings.Layout = nextString(sim.LayoutNames(), l.settings.Layout)
+	case inpututil.IsKeyJustPressed(ebiten.Key7):
+		if l.settings.Food == nil {
+			l.settings.Food = sim.DefaultFood
+		} else {
+			l.settings.Food = nil
+		}
+	}
+	return nil
+}
+
+func describeSettings(s netplay.Settings) string {
+	d := fmt.Sprintf("%s, %dx%d, %s walls, %s, up to %d players", s.Mode, s.Width, s.Height, s.Walls, s.Speed, s.MaxPlayers)
+	if s.Layout != "" && s.Layout != sim.LayoutOpen {
+		d += ", " + s.Layout
+	}
+	if s.PowerUps {
+		d += ", power-ups"
+	}
+	if f := sim.FormatFood(s.Food); f != "" {
+		d += ", " + f
+	}
+	return d
+}
+
+func (g *Game) drawLobby(screen *ebiten.Image) {
+	l, c := g.lobby, g.client
+	var b strings.Builder
+	if r := c.Room(); r != nil {
+		fmt.Fprintf(&b, "Room %d: %s\n%s\n\n", r.ID, r.Name, describeSettings(r.Settings))
+		for _, p := range r.Players {
+			mark := "waiting"
+			if p.Ready {
+				mark = "ready"
+			}
+			fmt.Fprintf(&b, "  %-16s %s\n", p.Name, mark)
+		}
+		b.WriteString("\nEnter: ready / not ready  Esc: leave\nThe game starts when everyone is ready.\n")
+	} else {
+		b.WriteString("Lobby\n\n")
+		rooms := c.Rooms()
+		if len(rooms) == 0 {
+			b.WriteString("  No rooms yet\n")
+		}
+		for i, r := range rooms {
+			cursor := " "
+			if i == l.cursor {
+				cursor = ">"
+			}
+			state := "open"
+			if r.Started {
+				state = "playing"
+			}
+			fmt.Fprintf(&b, "%s %-20s %d/%d %-8s %s\n", cursor, r.Name, len(r.Players), r.Settings.MaxPlayers, state, describeSettings(r.Settings))
+		}
+		fmt.Fprintf(&b, "\nNew room: %s\n", describeSettings(l.settings))
+		b.WriteString("1: size  2: walls  3: speed  4: players  5: mode  6: power-ups  7: food  8: layout\n")
+		b.WriteString("\nUp/Down: choose  Enter: join  N: create  R: refresh\n")
+	}
+	if msg := c.Refused(); msg != "" {
+		fmt.Fprintf(&b, "\n%s\n", msg)
+	}
+	drawText(screen, b.String(), textFace, 4, 4, g.theme.HUD.Text)
+}
@@ -1,13 +1,23 @@
 package main
 
 import (
+	"bytes"
+	"flag"
 	"fmt"
-	"image/color"
+	"image/png"
 	"log"
-	"math"
-	"math/rand"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
 	"time"
 
+	"ebiten/Snake/netplay"
+	"ebiten/Snake/scores"
+	"ebiten/Snake/settings"
+	"ebiten/Snake/sim"
+	"ebiten/Snake/theme"
+
 	"github.com/hajimehoshi/ebiten"
 	"github.com/hajimehoshi/ebiten/ebitenutil"
 	"github.com/hajimehoshi/ebiten/inpututil"
@@ -21,229 +31,610 @@ const (
 	yNumInScreen = screenHeight / gridSize
 )
 
-const (
-	dirNone = iota
-	dirLeft
-	dirRight
-	dirDown
-	dirUp
-)
-
-type Position struct {
-	X int
-	Y int
-}
+// playerID is the id of the local player's snake.
+const playerID = 1
 
 type Game struct {
-	moveDirection int
-	snakeBody     []Position
-	apple         Position
-	timer         int
-	moveTime      int
-	score         int
-	bestScore     int
-	level         int
-	gameMode      bool
-	prevLength    int
+	world *sim.World
+
+	// cfg, players and bots describe a local game so it can start over.
+	// The players have the snakes from id on, the bots those after them.
+	cfg     sim.Config
+	players int
+	bots    int
+	name    string
+
+	// scores are the high-score tables of the challenges. recorded is set
+	// once the game has been entered into them, at place rank if it made
+	// its table.
+	scores   *scores.Book
+	recorded bool
+	rank     int
+
+	// daily is the date of the daily challenge being played, replay the
+	// record of it. board is the URL of the shared daily leaderboard, and
+	// dailyTable and boardMsg what it said after the game, which arrives
+	// on boardResult.
+	daily       string
+	replay      *sim.Replay
+	board       string
+	dailyTable  []scores.Entry
+	boardMsg    string
+	boardResult chan boardResult
+
+	// playback is a replay being watched, next its next step.
+	playback *sim.Replay
+	next     int
+
+	// canvas is what the board is drawn on, then put below the HUD.
+	// popups float up from where the player scored, lastScore and
+	// lastCrashes are what the player's were last frame.
+	canvas      *ebiten.Image
+	popups      []popup
+	lastScore   int
+	lastCrashes int
+
+	// frame is what the HUD and the board make together, scale times as
+	// large on the screen with offsetX and offsetY to its top left corner.
+	frame            *ebiten.Image
+	scale            float64
+	offsetX, offsetY float64
+
+	// particles fly off where apples are eaten and snakes crash, and shake
+	// counts down the frames the board shakes for. lastSnakes and
+	// lastApple are what the board was like last frame.
+	particles  []particle
+	shake      int
+	lastSnakes map[int]lastSnake
+	lastApple  sim.Position
+
+	// debug shows the developer overlay, F3 toggles it. frames counts
+	// Update calls, inputs are the player's last few and mem is read
+	// for the overlay once a second.
+	debug  bool
+	frames int
+	inputs []loggedInput
+	mem    runtime.MemStats
+
+	// client is set when playing on a server. world is then the
+	// client's prediction of the server's board.
+	client *netplay.Client
+	lobby  *lobby
+	id     int
+
+	// peer is set when playing lockstep with other peers. world is then
+	// the peer's copy of the shared board.
+	peer *netplay.Peer
+
+	// stream publishes a local board to spectators.
+	stream *netplay.Stream
+
+	// paused stops a local game for single stepping and slow picks one of
+	// the slowdowns. history holds the world before each of the last
+	// movement ticks, to rewind to. assist adds up the speed assist's
+	// percentages until a frame's worth runs.
+	paused  bool
+	slow    int
+	history []*sim.World
+	assist  int
+
+	// prefs are the player's settings and bindings the keys of each
+	// action. keyScreen is set while the keys are being rebound. theme is
+	// what the game is drawn in, one of themes.
+	prefs     *settings.Settings
+	bindings  map[string][]ebiten.Key
+	keyScreen *keyScreen
+	themes    []*theme.Theme
+	theme     *theme.Theme
+
+	// pads are the gamepads of the local players, nil where a player has
+	// none. notice is a message for the HUD until frame noticeUntil.
+	pads        []*gamepad
+	notice      string
+	noticeUntil int
+
+	// touches are the fingers on the screen and touched is set once there
+	// has been one. touchDir and tapped are what they did this frame.
+	touches  map[int]*touch
+	touched  bool
+	touchDir int
+	tapped   bool
+
+	// mouse steers the snake towards the mouse pointer, mouseTurnAt is
+	// where its head was when it last turned that way.
+	mouse       bool
+	mouseTurnAt sim.Position
+
+	// spectator is set when watching someone else's board. id is then the
+	// followed snake and path its AI's plan.
+	spectator *netplay.Spectator
+	path      []sim.Position
 }
 
-func init() {
-	rand.Seed(time.Now().UnixNano())
+func (g *Game) player() *sim.Snake {
+	return g.world.Snake(g.id)
 }
 
-func (g *Game) collidesWithApple() bool {
-	return g.snakeBody[0].X == g.apple.X &&
-		g.snakeBody[0].Y == g.apple.Y
+// send passes the player's input on to the server.
+func (g *Game) send(in sim.Input) error {
+	switch {
+	case in.Dir != sim.DirNone:
+		return g.client.Turn(in.Dir)
+	case in.Reset:
+		return g.client.Reset()
+	case in.ToggleAI:
+		return g.client.ToggleAI()
+	}
+	return nil
 }
 
-func (g *Game) collidesWithSelf() bool {
-	for _, v := range g.snakeBody[1:] {
-		if g.snakeBody[0].X == v.X &&
-			g.snakeBody[0].Y == v.Y {
-			return true
+// follow moves the spectator on to the next snake on the board.
+func (g *Game) follow() error {
+	if g.world == nil || len(g.world.Snakes) == 0 {
+		return nil
+	}
+	next := g.world.Snakes[0].ID
+	for i, s := range g.world.Snakes {
+		if s.ID == g.id && i+1 < len(g.world.Snakes) {
+			next = g.world.Snakes[i+1].ID
 		}
 	}
-	return false
+	g.id = next
+	return g.spectator.Follow(g.id)
 }
 
-func (g *Game) collidesWithWall() bool {
-	return g.snakeBody[0].X < 0 ||
-		g.snakeBody[0].Y < 0 ||
-		g.snakeBody[0].X >= xNumInScreen ||
-		g.snakeBody[0].Y >= yNumInScreen
+func (g *Game) updateSpectator() error {
+	if inpututil.IsKeyJustPressed(ebiten.KeyTab) {
+		if err := g.follow(); err != nil {
+			return err
+		}
+	}
+	w, path, err := g.spectator.Latest()
+	if err == netplay.ErrNoSnapshot {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	g.world, g.path = w, path
+	return nil
 }
 
-func (g *Game) needsToMoveSnake() bool {
-	return g.timer%g.moveTime == 0
+func (g *Game) Update(screen *ebiten.Image) error {
+	defer g.updateGamepads()
+	if g.waitForFocus() {
+		// A local game waits for the player to come back, paused if it
+		// can be.
+		g.paused = g.paused || g.canStep()
+		return nil
+	}
+	if g.updateKeyScreen() && g.peer == nil {
+		// The peers can't wait for us.
+		return nil
+	}
+	g.updateTouches()
+	g.updateScreen()
+	err := g.update()
+	g.updateSounds()
+	g.updatePopups()
+	g.updateEffects()
+	g.updateDebug()
+	return err
 }
 
-func (g *Game) reset() {
-	g.apple.X = 3 * gridSize
-	g.apple.Y = 3 * gridSize
-	g.moveTime = 4
-	g.snakeBody = g.snakeBody[:1]
-	g.snakeBody[0].X = xNumInScreen / 2
-	g.snakeBody[0].Y = yNumInScreen / 2
-	g.score = 0
-	g.level = 1
-	g.moveDirection = dirNone
-}
+func (g *Game) update() error {
+	if g.spectator != nil {
+		return g.updateSpectator()
+	}
 
-func (g *Game) AIMovement() {
-	// AI movement
-	if g.gameMode {
-		length := int(math.Hypot(float64(g.snakeBody[0].X*gridSize-g.apple.X*gridSize),
-			float64(g.snakeBody[0].Y*gridSize-g.apple.Y*gridSize)))
-		if g.prevLength == 0 {
-			g.prevLength = length
+	in := g.readInput(0)
+	g.logInput(in)
+	var err error
+	switch {
+	case g.peer != nil:
+		g.world, err = g.peer.Update(in)
+		return err
+	case g.client != nil:
+		if !g.client.Playing() {
+			return g.updateLobby()
+		}
+		if g.menu(ebiten.KeyBackspace, settings.ButtonBack) {
+			return g.client.Leave()
 		}
-		if length < g.prevLength && length != gridSize {
-			// Keep on moving in the same direction
-		} else {
-			// Find if we have to move up/down/left/right.
-			switch g.moveDirection {
-			case dirRight:
-				if g.apple.Y > g.snakeBody[0].Y {
-					g.moveDirection = dirDown
-				} else {
-					g.moveDirection = dirUp
-				}
-			case dirLeft:
-				if g.apple.Y > g.snakeBody[0].Y {
-					g.moveDirection = dirDown
-				} else {
-					g.moveDirection = dirUp
-				}
-			case dirDown:
-				if g.apple.X > g.snakeBody[0].X {
-					g.moveDirection = dirRight
-				} else {
-					g.moveDirection = dirLeft
-				}
-			case dirUp:
-				if g.apple.X > g.snakeBody[0].X {
-					g.moveDirection = dirRight
-				} else {
-					g.moveDirection = dirLeft
-				}
-			}
+		if err := g.send(in); err != nil {
+			return err
 		}
-		g.prevLength = length
+		g.world, err = g.client.Update()
+		return err
+	}
+
+	if in.Reset && g.world.Mode != sim.ModeClassic {
+		g.restart()
+		return nil
 	}
+	if !g.stepControls() {
+		// Turns still count while the game stands still.
+		g.applyLocal(in)
+		return nil
+	}
+	switch {
+	case g.playback != nil:
+		g.next = g.playback.Apply(g.world, g.next)
+	case g.replay != nil:
+		// The AI doesn't get to play the daily challenge.
+		in.ToggleAI = false
+		g.replay.Record(g.world, in)
+		g.world.Apply(g.id, in)
+	default:
+		g.applyLocal(in)
+	}
+	g.wakeBots()
+	g.updateWorld()
+	g.record()
+	select {
+	case res := <-g.boardResult:
+		g.dailyTable, g.boardMsg = res.table, res.msg
+	default:
+	}
+
+	return nil
 }
 
-func (g *Game) Update(screen *ebiten.Image) error {
-	if inpututil.IsKeyJustPressed(ebiten.KeyLeft) || inpututil.IsKeyJustPressed(ebiten.KeyA) {
-		if g.moveDirection != dirRight {
-			g.moveDirection = dirLeft
+// Draw puts the HUD at the top of the frame and the board below it, and
+// the frame on the screen.
+func (g *Game) Draw(screen *ebiten.Image) {
+	if g.canvas == nil {
+		g.canvas, _ = ebiten.NewImage(screenWidth, screenHeight, ebiten.FilterDefault)
+		g.frame, _ = ebiten.NewImage(frameWidth, frameHeight, ebiten.FilterDefault)
+	}
+	g.frame.Clear()
+	g.canvas.Fill(g.theme.Background)
+	if g.keyScreen != nil {
+		g.drawKeyScreen(g.canvas)
+	} else {
+		g.drawBoard(g.canvas)
+		g.drawParticles(g.canvas)
+	}
+	dx, dy := g.shakeOffset()
+	op := &ebiten.DrawImageOptions{}
+	op.GeoM.Translate(dx, hudHeight+dy)
+	g.frame.DrawImage(g.canvas, op)
+	g.drawHUD(g.frame)
+	g.drawDPad(g.frame)
+	g.present(screen)
+}
+
+func (g *Game) drawBoard(screen *ebiten.Image) {
+	if g.client != nil && !g.client.Playing() {
+		g.drawLobby(screen)
+		return
+	}
+	if g.world == nil {
+		drawText(screen, "Waiting for the game", textFace, 4, 4, g.theme.HUD.Text)
+		return
+	}
+	if bw, bh := g.world.Width*gridSize, g.world.Height*gridSize; bw < screenWidth || bh < screenHeight {
+		// Grey out what lies beyond a small board.
+		c := g.theme.Outside
+		ebitenutil.DrawRect(screen, float64(bw), 0, float64(screenWidth-bw), screenHeight, c)
+		ebitenutil.DrawRect(screen, 0, float64(bh), float64(bw), float64(screenHeight-bh), c)
+	}
+	if g.debug || g.theme.Grid.Visible() {
+		g.drawGrid(screen)
+	}
+	p := g.player()
+	for _, v := range g.path {
+		ebitenutil.DrawRect(screen, float64(v.X*gridSize), float64(v.Y*gridSize), gridSize, gridSize, g.theme.Path)
+	}
+	for _, s := range g.world.Snakes {
+		c := g.theme.Player
+		if s != p {
+			c = g.theme.Others
 		}
-	} else if inpututil.IsKeyJustPressed(ebiten.KeyRight) || inpututil.IsKeyJustPressed(ebiten.KeyD) {
-		if g.moveDirection != dirLeft {
-			g.moveDirection = dirRight
+		for i, v := range s.Body {
+			g.drawCell(screen, v, c.Segment(i, len(s.Body)))
 		}
-	} else if inpututil.IsKeyJustPressed(ebiten.KeyDown) || inpututil.IsKeyJustPressed(ebiten.KeyS) {
-		if g.moveDirection != dirUp {
-			g.moveDirection = dirDown
+	}
+	for _, v := range g.world.Walls {
+		g.drawCell(screen, v, g.theme.Walls)
+	}
+	for _, f := range g.world.Food {
+		if f.Kind == sim.FoodRabbit && rabbitImage != nil && g.theme.Sprites == theme.SpritesPictures && !g.prefs.FoodShapes {
+			w, h := rabbitImage.Size()
+			op := &ebiten.DrawImageOptions{}
+			op.GeoM.Scale(gridSize/float64(w), gridSize/float64(h))
+			op.GeoM.Translate(float64(f.Pos.X*gridSize), float64(f.Pos.Y*gridSize))
+			screen.DrawImage(rabbitImage, op)
+			continue
 		}
-	} else if inpututil.IsKeyJustPressed(ebiten.KeyUp) || inpututil.IsKeyJustPressed(ebiten.KeyW) {
-		if g.moveDirection != dirDown {
-			g.moveDirection = dirUp
+		g.drawFood(screen, f.Pos, f.Kind, g.theme.Food[f.Kind])
+	}
+	for i, v := range g.world.Portals {
+		c := g.theme.Portals[i%len(g.theme.Portals)]
+		for _, p := range []sim.Position{v.A, v.B} {
+			g.drawCell(screen, p, c)
 		}
-	} else if inpututil.IsKeyJustPressed(ebiten.KeyEscape) {
-		g.reset()
-	} else if inpututil.IsKeyJustPressed(ebiten.KeySpace) {
-		g.gameMode = !g.gameMode
+	}
+	for _, v := range g.world.PowerUps {
+		g.drawCell(screen, v.Pos, g.theme.PowerUps[v.Kind])
+	}
+	g.drawFood(screen, g.world.Apple, sim.FoodApple, g.theme.Apple)
+	g.drawPopups(screen)
+	g.drawMouseTarget(screen)
+	if g.debug {
+		g.drawDebug(screen)
 	}
 
-	if g.needsToMoveSnake() {
-		g.AIMovement()
-
-		if g.collidesWithWall() || g.collidesWithSelf() {
-			g.reset()
+	if g.world.Over {
+		msg := g.result()
+		if sim.IsChallenge(g.world.Mode) {
+			msg = g.challengeResult()
 		}
+		drawText(screen, msg, textFace, 4, 20, g.theme.HUD.Text)
+	}
+}
 
-		if g.collidesWithApple() {
-			g.apple.X = rand.Intn(xNumInScreen - 1)
-			g.apple.Y = rand.Intn(yNumInScreen - 1)
-			g.snakeBody = append(g.snakeBody, Position{
-				X: g.snakeBody[len(g.snakeBody)-1].X,
-				Y: g.snakeBody[len(g.snakeBody)-1].Y,
-			})
-			if len(g.snakeBody) > 10 && len(g.snakeBody) < 20 {
-				g.level = 2
-				g.moveTime = 3
-			} else if len(g.snakeBody) > 20 {
-				g.level = 3
-				g.moveTime = 2
-			} else {
-				g.level = 1
-			}
-			g.score++
-			if g.bestScore < g.score {
-				g.bestScore = g.score
-			}
-		}
+// rabbitImage is drawn for rabbits when the theme has pictures, they are
+// squares of their colour otherwise.
+var rabbitImage *ebiten.Image
+
+func loadImages() {
+	img, err := png.Decode(bytes.NewReader(RabbitImage))
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	rabbitImage, _ = ebiten.NewImageFromImage(img, ebiten.FilterDefault)
+}
 
-		for i := int64(len(g.snakeBody)) - 1; i > 0; i-- {
-			g.snakeBody[i].X = g.snakeBody[i-1].X
-			g.snakeBody[i].Y = g.snakeBody[i-1].Y
+// effects lists the power-ups working on a snake and the seconds they have left.
+func (g *Game) effects(s *sim.Snake) string {
+	var b strings.Builder
+	for i, e := range s.Effects {
+		if i > 0 {
+			b.WriteString("  ")
 		}
-		switch g.moveDirection {
-		case dirLeft:
-			g.snakeBody[0].X--
-		case dirRight:
-			g.snakeBody[0].X++
-		case dirDown:
-			g.snakeBody[0].Y++
-		case dirUp:
-			g.snakeBody[0].Y--
+		fmt.Fprintf(&b, "%s %ds", e.Kind, (e.Until-g.world.Timer+ebiten.MaxTPS()-1)/ebiten.MaxTPS())
+	}
+	return b.String()
+}
+
+// result says who won a game that is over.
+func (g *Game) result() string {
+	again := ""
+	if g.client == nil && g.peer == nil && g.spectator == nil {
+		again = ", press Escape to play again"
+	}
+	switch s := g.world.Snake(g.world.Winner); {
+	case s == nil:
+		return "Nobody survived" + again
+	case s.ID == g.id && g.spectator == nil:
+		return fmt.Sprintf("You win with %d points%s", s.Score, again)
+	case s.Name != "":
+		return fmt.Sprintf("%s wins with %d points%s", s.Name, s.Score, again)
+	default:
+		return fmt.Sprintf("Snake %d wins with %d points%s", s.ID, s.Score, again)
+	}
+}
+
+// newGame plays locally on the board cfg describes, players at once on
+// this machine against bots AI snakes if any.
+func newGame(cfg sim.Config, players, bots int, name string) *Game {
+	if cfg.Width == 0 {
+		cfg.Width, cfg.Height = xNumInScreen, yNumInScreen
+	}
+	g := &Game{
+		cfg:         cfg,
+		players:     players,
+		bots:        bots,
+		id:          playerID,
+		name:        name,
+		boardResult: make(chan boardResult, 1),
+	}
+	if sim.IsChallenge(cfg.Mode) {
+		var err error
+		if g.scores, err = scores.Load(scores.Path()); err != nil {
+			log.Println(err)
 		}
 	}
+	g.restart()
+	return g
+}
 
-	g.timer++
+func (g *Game) restart() {
+	g.recorded, g.rank, g.dailyTable, g.boardMsg = false, 0, nil, ""
+	g.history = nil
+	if g.playback != nil {
+		g.world, g.next = g.playback.NewWorld(), 0
+		return
+	}
+	cfg := g.cfg
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+	g.world = sim.NewWorld(cfg)
+	if g.daily != "" {
+		g.replay = &sim.Replay{Date: g.daily, Name: g.name, Config: cfg}
+	}
+	g.world.AddSnake(g.id).Name = g.name
+	for i := 1; i < g.players; i++ {
+		g.world.AddSnake(g.id + i).Name = fmt.Sprintf("Player %d", i+1)
+	}
+	for i := 1; i <= g.bots; i++ {
+		s := g.world.AddSnake(g.id + g.players - 1 + i)
+		s.Name = fmt.Sprintf("Bot %d", i)
+		s.AI = true
+	}
+	g.applyPractice()
+}
 
-	return nil
+// applyLocal applies the first player's input and reads and applies the
+// other local players'.
+func (g *Game) applyLocal(in sim.Input) {
+	g.world.Apply(g.id, in)
+	for i := 1; i < g.players; i++ {
+		g.world.Apply(g.id+i, g.readInput(i))
+	}
 }
 
-func (g *Game) Draw(screen *ebiten.Image) {
-	for _, v := range g.snakeBody {
-		ebitenutil.DrawRect(screen, float64(v.X*gridSize), float64(v.Y*gridSize), gridSize, gridSize, color.RGBA{0x80, 0xa0, 0xc0, 0xff})
+// wakeBots gets the bots going once the player is.
+func (g *Game) wakeBots() {
+	if p := g.player(); p == nil || p.Dir == sim.DirNone {
+		return
 	}
-	ebitenutil.DrawRect(screen, float64(g.apple.X*gridSize), float64(g.apple.Y*gridSize), gridSize, gridSize, color.RGBA{0xFF, 0x00, 0x00, 0xff})
+	for _, s := range g.world.Snakes {
+		if s.ID != g.id && s.AI && !s.Dead && s.Dir == sim.DirNone {
+			s.Dir = sim.DirUp
+		}
+	}
+}
 
-	if g.moveDirection == dirNone {
-		ebitenutil.DebugPrint(screen, fmt.Sprintf("Press up/down/left/right to start"))
-	} else {
-		msg := func() string {
-			if g.gameMode {
-				return "AI"
-			}
-			return "Manual"
-		}()
-		ebitenutil.DebugPrint(screen, fmt.Sprintf("FPS: %0.2f Level: %d Score: %d Best Score: %d, %s, Len: %d", ebiten.CurrentFPS(), g.level, g.score, g.bestScore, msg, int(math.Hypot(float64(g.snakeBody[0].X*gridSize-g.apple.X*gridSize), float64(g.snakeBody[0].Y*gridSize-g.apple.Y*gridSize)))))
+// newDailyGame plays today's daily challenge, sending the result to the
+// shared leaderboard at board if there is one.
+func newDailyGame(name, board string) *Game {
+	date := netplay.Today()
+	g := newGame(sim.Daily(date), 1, 0, name)
+	g.daily, g.board = date, board
+	g.restart()
+	return g
+}
+
+// newReplayGame watches a replay saved by a daily challenge.
+func newReplayGame(path string) (*Game, error) {
+	rp, err := scores.LoadReplay(path)
+	if err != nil {
+		return nil, err
 	}
-	ebitenutil.DrawLine(screen, float64(g.snakeBody[0].X*gridSize), float64(g.snakeBody[0].Y*gridSize), float64(g.apple.X*gridSize), float64(g.apple.Y*gridSize), color.RGBA{0x00, 0x00, 0xFF, 0xFF})
+	g := &Game{id: sim.ReplayID, name: rp.Name, playback: rp}
+	g.restart()
+	return g, nil
 }
 
-func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
-	return screenWidth, screenHeight
+// newNetworkGame enters the lobby of the server at addr instead of running
+// the rules locally.
+func newNetworkGame(addr, name string) (*Game, error) {
+	c, err := netplay.Connect(addr, name)
+	if err != nil {
+		return nil, err
+	}
+	return &Game{client: c, lobby: newLobby(), id: c.ID}, nil
 }
 
-func newGame() *Game {
-	g := &Game{
-		apple:     Position{X: 3 * gridSize, Y: 3 * gridSize},
-		moveTime:  4,
-		snakeBody: make([]Position, 1),
+// newSpectatorGame watches the stream at addr without playing.
+func newSpectatorGame(addr string, follow int) (*Game, error) {
+	sp, err := netplay.Watch(addr, follow)
+	if err != nil {
+		return nil, err
 	}
-	g.snakeBody[0].X = xNumInScreen / 2
-	g.snakeBody[0].Y = yNumInScreen / 2
-	return g
+	return &Game{spectator: sp, id: follow}, nil
+}
+
+// newPeerGame plays lockstep with the peers at addrs, index being ours.
+func newPeerGame(index int, addrs []string, delay int) (*Game, error) {
+	p, err := netplay.NewPeer(index, addrs, delay, sim.Config{
+		Width:  xNumInScreen,
+		Height: yNumInScreen,
+		Seed:   time.Now().UnixNano(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	g := &Game{peer: p, id: index + 1}
+	g.world, err = p.Update(sim.Input{})
+	if err != nil {
+		return nil, err
+	}
+	return g, nil
 }
 
 func main() {
-	ebiten.SetWindowSize(screenWidth, screenHeight)
+	connect := flag.String("connect", "", "play on a snake server, host:port or ws://host:port/ws")
+	name := flag.String("name", "player", "player name on the server and in the high scores")
+	watch := flag.String("watch", "", "spectate a game, ws://host:port/watch")
+	follow := flag.Int("follow", playerID, "id of the snake to follow when spectating")
+	streamAddr := flag.String("stream", "", "let spectators watch this game at ws://<addr>/watch")
+	peers := flag.String("peers", "", "play lockstep without a server, comma separated host:port of every peer")
+	peerIndex := flag.Int("peer", 0, "which of -peers is this one")
+	delay := flag.Int("delay", 2, "frames of input delay when playing lockstep")
+	royale := flag.Bool("royale", false, "battle royale on a shrinking board, last snake alive wins, same as -mode royale")
+	mode := flag.String("mode", sim.ModeClassic, "classic, royale, or one of the challenges timeattack, sprint and survival")
+	timeLimit := flag.Int("time", 60, "seconds of a time attack")
+	sprintLength := flag.Int("length", 30, "length to reach in a sprint")
+	players := flag.Int("players", 1, "local players, the first on the keyboard and gamepads in the order they are plugged in")
+	bots := flag.Int("bots", 0, "number of AI snakes to play against")
+	powerUps := flag.Bool("powerups", false, "let power-ups turn up on the board")
+	layout := flag.String("layout", sim.LayoutOpen, "what is on the board: "+strings.Join(sim.LayoutNames(), ", "))
+	daily := flag.Bool("daily", false, "play today's daily challenge, the same game for everybody")
+	board := flag.String("board", "", "shared daily challenge leaderboard, http://host:port/daily")
+	replay := flag.String("replay", "", "watch a replay of a daily challenge")
+	mouse := flag.Bool("mouse", false, "steer towards the mouse pointer")
+	debug := flag.Bool("debug", false, "start with the developer overlay on, F3 toggles it")
+	foodFlag := flag.String("food", "", "food on the board at once besides the apple, e.g. "+sim.FormatFood(sim.DefaultFood))
+	os.Args = append(os.Args, pageArgs()...)
+	flag.Parse()
+
+	food, err := sim.ParseFood(*foodFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if _, ok := sim.Layouts[*layout]; !ok {
+		log.Fatalf("unknown layout %q", *layout)
+	}
+
+	if *royale {
+		*mode = sim.ModeRoyale
+	}
+	if *players < 1 {
+		log.Fatal("-players needs at least one")
+	}
+	switch {
+	case *mode == sim.ModeRoyale:
+		if *bots == 0 {
+			*bots = 3
+		}
+	case *mode != sim.ModeClassic && !sim.IsChallenge(*mode):
+		log.Fatalf("unknown mode %q", *mode)
+	}
+	g := newGame(sim.Config{
+		Mode:         *mode,
+		PowerUps:     *powerUps,
+		Food:         food,
+		Layout:       *layout,
+		TimeLimit:    *timeLimit * ebiten.MaxTPS(),
+		SprintLength: *sprintLength,
+	}, *players, *bots, *name)
+	switch {
+	case *daily:
+		g = newDailyGame(*name, *board)
+	case *replay != "":
+		g, err = newReplayGame(*replay)
+	case *connect != "":
+		g, err = newNetworkGame(*connect, *name)
+	case *watch != "":
+		g, err = newSpectatorGame(*watch, *follow)
+	case *peers != "":
+		g, err = newPeerGame(*peerIndex, strings.Split(*peers, ","), *delay)
+	case *streamAddr != "":
+		g.stream = netplay.NewStream()
+		mux := http.NewServeMux()
+		mux.Handle("/watch", g.stream)
+		go func() {
+			log.Println(http.ListenAndServe(*streamAddr, mux))
+		}()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	g.debug, g.mouse = *debug, *mouse
+	g.loadSettings()
+	loadImages()
+	loadFonts()
+	g.setupScreen()
+	// Network games go on without us, so Update has to as well.
+	ebiten.SetRunnableOnUnfocused(true)
 	ebiten.SetWindowTitle("Snake (Ebiten Demo)")
-	if err := ebiten.RunGame(newGame()); err != nil {
+	if err := ebiten.RunGame(g); err != nil {
 		log.Fatal(err)
 	}
 }
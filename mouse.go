@@ -0,0 +1,106 @@
+package main
+
+import (
+	"image/color"
+
+	"ebiten/Snake/sim"
+
+	"github.com/hajimehoshi/ebiten"
+	"github.com/hajimehoshi/ebiten/ebitenutil"
+	"github.com/hajimehoshi/ebiten/inpututil"
+)
+
+var mouseTarget = color.RGBA{0xff, 0xff, 0xff, 0xa0}
+
+// dirVectors are the ways the snake can go as steps on the screen.
+var dirVectors = map[int][2]int{
+	sim.DirLeft:  {-1, 0},
+	sim.DirRight: {1, 0},
+	sim.DirDown:  {0, 1},
+	sim.DirUp:    {0, -1},
+}
+
+// mouseSteering reports whether the player steers with the mouse, which
+// the -mouse flag or the settings turn on.
+func (g *Game) mouseSteering() bool {
+	return (g.mouse || g.prefs.MouseSteering) && g.keyScreen == nil
+}
+
+// mouseCell returns the cell the mouse is over and whether it is over the
+// board at all.
+func (g *Game) mouseCell() (sim.Position, bool) {
+	x, y := g.cursor()
+	y -= hudHeight
+	if g.world == nil || x < 0 || y < 0 || x >= g.world.Width*gridSize || y >= g.world.Height*gridSize {
+		return sim.Position{}, false
+	}
+	return sim.Position{X: x / gridSize, Y: y / gridSize}, true
+}
+
+// mouseTurn returns the turn that points the first local player's snake
+// most nearly at the mouse when steering with it: straight on, or 90
+// degrees either way. A snake that hasn't started yet goes whichever way is
+// best once the mouse is clicked. The snake turns at most once a move, so
+// two quick turns can't take it back into itself.
+func (g *Game) mouseTurn(player int) int {
+	if player != 0 || !g.mouseSteering() || g.world == nil {
+		return sim.DirNone
+	}
+	p := g.player()
+	if p == nil || p.Dead || len(p.Body) == 0 {
+		return sim.DirNone
+	}
+	target, ok := g.mouseCell()
+	head := p.Head()
+	if !ok || target == head {
+		return sim.DirNone
+	}
+
+	var turns []int
+	switch p.Dir {
+	case sim.DirNone:
+		if !inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) {
+			return sim.DirNone
+		}
+		turns = []int{sim.DirLeft, sim.DirRight, sim.DirDown, sim.DirUp}
+	case sim.DirLeft, sim.DirRight:
+		turns = []int{p.Dir, sim.DirUp, sim.DirDown}
+	default:
+		turns = []int{p.Dir, sim.DirLeft, sim.DirRight}
+	}
+	if p.Dir != sim.DirNone && head == g.mouseTurnAt {
+		return sim.DirNone
+	}
+
+	// The turn with the largest dot product with the way to the mouse is
+	// the one with the smallest angle to it. Ties keep going straight.
+	dx, dy := target.X-head.X, target.Y-head.Y
+	best, bestDot := sim.DirNone, 0
+	for _, d := range turns {
+		v := dirVectors[d]
+		if dot := v[0]*dx + v[1]*dy; best == sim.DirNone || dot > bestDot {
+			best, bestDot = d, dot
+		}
+	}
+	if best == p.Dir {
+		return sim.DirNone
+	}
+	g.mouseTurnAt = head
+	return best
+}
+
+// drawMouseTarget outlines the cell the snake is steering for.
+func (g *Game) drawMouseTarget(board *ebiten.Image) {
+	if !g.mouseSteering() {
+		return
+	}
+	c, ok := g.mouseCell()
+	if !ok {
+		return
+	}
+	x, y := float64(c.X*gridSize), float64(c.Y*gridSize)
+	ebitenutil.DrawRect(board, x-1, y-1, gridSize+2, 1, mouseTarget)
+	ebitenutil.DrawRect(board, x-1, y+gridSize, gridSize+2, 1, mouseTarget)
+	ebitenutil.DrawRect(board, x-1, y, 1, gridSize, mouseTarget)
+	ebitenutil.DrawRect(board, x+gridSize, y, 1, gridSize, mouseTarget)
+}
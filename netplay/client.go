@@ -0,0 +1,261 @@
+package netplay
+
+import (
+	"errors"
+	"sync"
+
+	"ebiten/Snake/sim"
+)
+
+// Client is a connection to a server from a player's point of view. It starts
+// out in the lobby; once the player's room has started, the player's own
+// snake is predicted locally so turning feels immediate even though the
+// server decides what really happens.
+type Client struct {
+	ID int
+
+	conn Conn
+
+	mu      sync.Mutex
+	rooms   []RoomInfo
+	room    *RoomInfo
+	refused string
+	snap    *sim.World
+	frames  int
+	seq     int
+	pending []Message
+	err     error
+}
+
+// Connect dials addr and enters the lobby as name.
+func Connect(addr, name string) (*Client, error) {
+	conn, err := Dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Send(&Message{Type: MsgHello, Name: name}); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	var m Message
+	if err := conn.Recv(&m); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if m.Type == MsgError {
+		conn.Close()
+		return nil, errors.New(m.Error)
+	}
+	if m.Type != MsgWelcome {
+		conn.Close()
+		return nil, errors.New("unexpected " + m.Type + " from server")
+	}
+	// The rooms always follow, so the lobby is never seen empty by mistake.
+	var rooms Message
+	if err := conn.Recv(&rooms); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c := &Client{
+		ID:    m.ID,
+		conn:  conn,
+		rooms: rooms.Rooms,
+	}
+	go c.read()
+	return c, nil
+}
+
+func (c *Client) read() {
+	for {
+		var m Message
+		if err := c.conn.Recv(&m); err != nil {
+			c.fail(err)
+			return
+		}
+		if m.Type == MsgError {
+			c.fail(errors.New(m.Error))
+			return
+		}
+		c.mu.Lock()
+		switch m.Type {
+		case MsgRooms:
+			c.rooms = m.Rooms
+		case MsgRefused:
+			c.refused = m.Error
+			if c.room != nil && c.room.ID == 0 {
+				c.room = nil
+			}
+		case MsgRoom:
+			if c.room != nil {
+				c.room = m.Room
+				c.refused = ""
+				if !m.Room.Started {
+					c.snap = nil
+				}
+			}
+		case MsgStart:
+			c.room = m.Room
+			c.snap = m.World
+			c.frames = 0
+			c.pending = nil
+		case MsgSnapshot:
+			if c.snap == nil {
+				break
+			}
+			c.snap = m.World
+			c.frames = 0
+			i := 0
+			for i < len(c.pending) && c.pending[i].Seq <= m.Ack {
+				i++
+			}
+			c.pending = c.pending[i:]
+		}
+		c.mu.Unlock()
+	}
+}
+
+func (c *Client) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+// Rooms returns the rooms last seen in the lobby.
+func (c *Client) Rooms() []RoomInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.rooms
+}
+
+// Room returns the player's room, nil while in the lobby.
+func (c *Client) Room() *RoomInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.room
+}
+
+// Refused returns why the server turned down the last request, if it did.
+func (c *Client) Refused() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.refused
+}
+
+// Playing reports whether the player's room has started.
+func (c *Client) Playing() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.snap != nil
+}
+
+// List asks for the rooms again.
+func (c *Client) List() error {
+	return c.conn.Send(&Message{Type: MsgList})
+}
+
+// Create opens a new room and joins it.
+func (c *Client) Create(name string, settings Settings) error {
+	c.enter()
+	return c.conn.Send(&Message{Type: MsgCreate, Room: &RoomInfo{Name: name, Settings: settings}})
+}
+
+// Join enters the room with the given id.
+func (c *Client) Join(id int) error {
+	c.enter()
+	return c.conn.Send(&Message{Type: MsgJoin, ID: id})
+}
+
+// enter makes the client take the room the server is about to describe.
+func (c *Client) enter() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refused = ""
+	if c.room == nil {
+		c.room = &RoomInfo{}
+	}
+}
+
+// Leave goes back to the lobby.
+func (c *Client) Leave() error {
+	c.mu.Lock()
+	c.room = nil
+	c.snap = nil
+	c.mu.Unlock()
+	return c.conn.Send(&Message{Type: MsgLeave})
+}
+
+// Ready tells the room whether the player is ready to start.
+func (c *Client) Ready(ready bool) error {
+	return c.conn.Send(&Message{Type: MsgReady, Ready: ready})
+}
+
+// Turn asks the server to change the player's direction.
+func (c *Client) Turn(dir int) error {
+	c.mu.Lock()
+	c.seq++
+	m := Message{Type: MsgInput, Seq: c.seq, Dir: dir}
+	c.pending = append(c.pending, m)
+	c.mu.Unlock()
+	return c.conn.Send(&m)
+}
+
+// Pending returns how many of the player's turns the server has not
+// acknowledged yet.
+func (c *Client) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.pending)
+}
+
+// ToggleAI lets the server's AI steer the player's snake or hands it back.
+func (c *Client) ToggleAI() error {
+	return c.conn.Send(&Message{Type: MsgToggleAI})
+}
+
+// Reset respawns the player's snake.
+func (c *Client) Reset() error {
+	return c.conn.Send(&Message{Type: MsgReset})
+}
+
+// Update is called once per frame and returns the board to draw: the last
+// snapshot with the player's unacknowledged turns applied and the player's
+// snake moved on by the frames that passed since. It returns nil while the
+// player is not in a started room.
+func (c *Client) Update() (*sim.World, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+	if c.snap == nil {
+		return nil, nil
+	}
+	c.frames++
+
+	w := c.snap.Clone()
+	s := w.Snake(c.ID)
+	if s == nil || s.Dead {
+		return w, nil
+	}
+	for _, m := range c.pending {
+		w.SetDirection(c.ID, m.Dir)
+	}
+	// Don't run ahead for long if the server has gone quiet.
+	moveTime := w.MoveTime(s)
+	frames := c.frames
+	if frames > 2*moveTime {
+		frames = 2 * moveTime
+	}
+	for i := 1; i < frames; i++ {
+		if (w.Timer+i-1)%moveTime == 0 {
+			w.Advance(s)
+		}
+	}
+	return w, nil
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
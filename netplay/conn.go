@@ -0,0 +1,90 @@
+package netplay
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+)
+
+// Conn sends and receives messages. Send may be called from several
+// goroutines, Recv from one.
+type Conn interface {
+	Send(m *Message) error
+	Recv(m *Message) error
+	Close() error
+}
+
+// Dial connects to a server. Addresses starting with ws:// or wss:// use
+// WebSocket, anything else is a plain TCP host:port.
+func Dial(addr string) (Conn, error) {
+	if strings.HasPrefix(addr, "ws://") || strings.HasPrefix(addr, "wss://") {
+		c, _, err := websocket.DefaultDialer.Dial(addr, nil)
+		if err != nil {
+			return nil, err
+		}
+		return NewWebSocketConn(c), nil
+	}
+	c, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewTCPConn(c), nil
+}
+
+// tcpConn sends one JSON message per line.
+type tcpConn struct {
+	conn net.Conn
+	dec  *json.Decoder
+	mu   sync.Mutex
+	enc  *json.Encoder
+}
+
+func NewTCPConn(c net.Conn) Conn {
+	return &tcpConn{
+		conn: c,
+		dec:  json.NewDecoder(bufio.NewReader(c)),
+		enc:  json.NewEncoder(c),
+	}
+}
+
+func (c *tcpConn) Send(m *Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.enc.Encode(m)
+}
+
+func (c *tcpConn) Recv(m *Message) error {
+	return c.dec.Decode(m)
+}
+
+func (c *tcpConn) Close() error {
+	return c.conn.Close()
+}
+
+// wsConn sends one JSON message per text frame.
+type wsConn struct {
+	conn *websocket.Conn
+	mu   sync.Mutex
+}
+
+func NewWebSocketConn(c *websocket.Conn) Conn {
+	return &wsConn{conn: c}
+}
+
+func (c *wsConn) Send(m *Message) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn.WriteJSON(m)
+}
+
+func (c *wsConn) Recv(m *Message) error {
+	return c.conn.ReadJSON(m)
+}
+
+func (c *wsConn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,203 @@
+package netplay
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"ebiten/Snake/scores"
+	"ebiten/Snake/sim"
+)
+
+// DailyBoard is the shared leaderboard of the daily challenge. Players post
+// their replays to it and it plays each one again to find the score, rather
+// than taking anyone's word for it.
+//
+//	GET  ?date=2006-01-02         the day's table, today's if no date
+//	GET  ?date=2006-01-02&rank=1  the replay of a place in the table
+//	POST a sim.Replay             enters the replay, answers with DailyResult
+type DailyBoard struct {
+	mu   sync.Mutex
+	book *scores.Book
+}
+
+// DailyResult is how a replay posted to a DailyBoard did.
+type DailyResult struct {
+	Score int `json:"score"`
+	Rank  int `json:"rank"`
+}
+
+const (
+	// maxReplay caps the size of a posted replay.
+	maxReplay = 1 << 20
+
+	// maxReplayFrames caps how long a posted replay may wait before its
+	// last move, so playing it again can't keep the server busy for long.
+	maxReplayFrames = 10 * 60 * 60
+)
+
+// NewDailyBoard keeps its tables and replays in the book at path, in memory
+// only if path is "".
+func NewDailyBoard(path string) (*DailyBoard, error) {
+	b, err := scores.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return &DailyBoard{book: b}, nil
+}
+
+// Today is the date of today's daily challenge. The day changes at midnight
+// UTC for everybody.
+func Today() string {
+	return time.Now().UTC().Format("2006-01-02")
+}
+
+// DailyTable names the table of a day's challenge.
+func DailyTable(date string) string {
+	return "daily-" + date
+}
+
+func (d *DailyBoard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// The game in a browser is usually served from somewhere else.
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	switch r.Method {
+	case http.MethodOptions:
+	case http.MethodGet:
+		date := r.URL.Query().Get("date")
+		if date == "" {
+			date = Today()
+		}
+		d.mu.Lock()
+		entries := append([]scores.Entry{}, d.book.Table(DailyTable(date))...)
+		d.mu.Unlock()
+		if rank := r.URL.Query().Get("rank"); rank != "" {
+			d.serveReplay(w, entries, rank)
+			return
+		}
+		for i := range entries {
+			// Where the server keeps replays is its own business.
+			entries[i].Replay = ""
+		}
+		writeJSON(w, entries)
+	case http.MethodPost:
+		var rp sim.Replay
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxReplay)).Decode(&rp); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		res, err := d.enter(&rp)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, res)
+	default:
+		http.Error(w, "GET or POST", http.StatusMethodNotAllowed)
+	}
+}
+
+func (d *DailyBoard) serveReplay(w http.ResponseWriter, entries []scores.Entry, rank string) {
+	n, err := strconv.Atoi(rank)
+	if err != nil || n < 1 || n > len(entries) || entries[n-1].Replay == "" {
+		http.Error(w, "no such replay", http.StatusNotFound)
+		return
+	}
+	rp, err := scores.LoadReplay(entries[n-1].Replay)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, rp)
+}
+
+// enter plays a replay of the day's challenge again and puts it in the table.
+func (d *DailyBoard) enter(rp *sim.Replay) (*DailyResult, error) {
+	today := time.Now().UTC()
+	if rp.Date != today.Format("2006-01-02") && rp.Date != today.AddDate(0, 0, -1).Format("2006-01-02") {
+		// Yesterday's still counts for a game that ran past midnight.
+		return nil, fmt.Errorf("%q is not today's challenge", rp.Date)
+	}
+	rp.Name = strings.TrimSpace(rp.Name)
+	if rp.Name == "" {
+		return nil, errors.New("a replay needs a name")
+	}
+	rp.Config = sim.Daily(rp.Date)
+	last := 0
+	for _, st := range rp.Steps {
+		if st.Frame > maxReplayFrames {
+			return nil, errors.New("the replay is too long")
+		}
+		if st.Input.ToggleAI {
+			// The AI can't play the challenge for anyone.
+			return nil, errors.New("the replay hands the snake to the AI")
+		}
+		if st.Frame > last {
+			last = st.Frame
+		}
+	}
+	world := rp.Play(last + rp.Config.TimeLimit + 1)
+	s := world.Snake(sim.ReplayID)
+	if !world.Over || s == nil {
+		return nil, errors.New("the replay does not finish the challenge")
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	table := DailyTable(rp.Date)
+	e := scores.Entry{Name: rp.Name, Score: world.ChallengeScore(s), Date: rp.Date}
+	if d.book.Place(table, world.LowerIsBetter(), e.Score) == 0 {
+		// Only the replays in the table are kept.
+		return &DailyResult{Score: e.Score}, nil
+	}
+	var err error
+	if e.Replay, err = d.book.SaveReplay(table, rp); err != nil {
+		return nil, err
+	}
+	res := &DailyResult{Score: e.Score, Rank: d.book.Add(table, world.LowerIsBetter(), e)}
+	return res, d.book.Save()
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// SubmitDaily posts a replay of the daily challenge to the DailyBoard at url.
+func SubmitDaily(url string, rp *sim.Replay) (*DailyResult, error) {
+	data, err := json.Marshal(rp)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("daily board: %s", resp.Status)
+	}
+	res := &DailyResult{}
+	return res, json.NewDecoder(resp.Body).Decode(res)
+}
+
+// FetchDaily returns the table of a day's challenge from the DailyBoard at
+// url.
+func FetchDaily(url, date string) ([]scores.Entry, error) {
+	resp, err := http.Get(url + "?date=" + date)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("daily board: %s", resp.Status)
+	}
+	var entries []scores.Entry
+	return entries, json.NewDecoder(resp.Body).Decode(&entries)
+}
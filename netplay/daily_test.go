@@ -0,0 +1,48 @@
+package netplay
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ebiten/Snake/scores"
+	"ebiten/Snake/sim"
+)
+
+func TestDailyReplays(t *testing.T) {
+	dir, err := ioutil.TempDir("", "daily")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	d, err := NewDailyBoard(filepath.Join(dir, "scores.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replays := func() int {
+		files, _ := ioutil.ReadDir(filepath.Join(dir, "replays"))
+		return len(files)
+	}
+	// A snake that sets off and never turns scores next to nothing.
+	post := func() *DailyResult {
+		res, err := d.enter(&sim.Replay{Date: Today(), Name: "test", Steps: []sim.Step{{Input: sim.Input{Dir: sim.DirRight}}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+
+	if res := post(); res.Rank != 1 || replays() != 1 {
+		t.Fatalf("rank %d with %d replays kept, want 1 and 1", res.Rank, replays())
+	}
+	for i := 0; i < scores.Size; i++ {
+		d.book.Add(DailyTable(Today()), false, scores.Entry{Name: "best", Score: 1000})
+	}
+	if replays() != 0 {
+		t.Fatalf("%d replays kept of entries pushed off the table", replays())
+	}
+	if res := post(); res.Rank != 0 || replays() != 0 {
+		t.Fatalf("rank %d with %d replays kept, want 0 and 0", res.Rank, replays())
+	}
+}
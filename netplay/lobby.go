@@ -0,0 +1,172 @@
+package netplay
+
+import (
+	"errors"
+	"fmt"
+
+	"ebiten/Snake/sim"
+)
+
+// Settings are chosen by whoever creates a room.
+type Settings struct {
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	Walls      string `json:"walls"` // WallsSolid or WallsWrap
+	Speed      string `json:"speed"` // a key of Speeds
+	MaxPlayers int    `json:"maxPlayers"`
+	Mode       string `json:"mode"` // sim.ModeClassic or sim.ModeRoyale
+	PowerUps   bool   `json:"powerUps,omitempty"`
+
+	// Food is how many of each sim.FoodKinds the board has besides the
+	// apple.
+	Food map[string]int `json:"food,omitempty"`
+
+	Layout string `json:"layout,omitempty"` // a key of sim.Layouts
+}
+
+const (
+	WallsSolid = "solid"
+	WallsWrap  = "wrap"
+)
+
+// Speeds maps speed presets to frames per cell at level 1.
+var Speeds = map[string]int{
+	"slow":   6,
+	"normal": 4,
+	"fast":   3,
+}
+
+// Board sizes a room may use. The largest fills the game window.
+const (
+	MinBoardWidth  = 16
+	MinBoardHeight = 12
+	MaxBoardWidth  = 64
+	MaxBoardHeight = 48
+)
+
+// DefaultSettings is the classic game for up to four players.
+var DefaultSettings = Settings{
+	Width:      MaxBoardWidth,
+	Height:     MaxBoardHeight,
+	Walls:      WallsSolid,
+	Speed:      "normal",
+	MaxPlayers: 4,
+	Mode:       sim.ModeClassic,
+	Layout:     sim.LayoutOpen,
+}
+
+func (s *Settings) validate(maxPlayers int) error {
+	if s.Width < MinBoardWidth || s.Width > MaxBoardWidth ||
+		s.Height < MinBoardHeight || s.Height > MaxBoardHeight {
+		return fmt.Errorf("board must be between %dx%d and %dx%d", MinBoardWidth, MinBoardHeight, MaxBoardWidth, MaxBoardHeight)
+	}
+	if s.Walls != WallsSolid && s.Walls != WallsWrap {
+		return fmt.Errorf("unknown walls mode %q", s.Walls)
+	}
+	if _, ok := Speeds[s.Speed]; !ok {
+		return fmt.Errorf("unknown speed %q", s.Speed)
+	}
+	if s.Mode == "" {
+		s.Mode = sim.ModeClassic
+	}
+	if s.Mode != sim.ModeClassic && s.Mode != sim.ModeRoyale {
+		return fmt.Errorf("unknown mode %q", s.Mode)
+	}
+	if s.Layout == "" {
+		s.Layout = sim.LayoutOpen
+	}
+	if _, ok := sim.Layouts[s.Layout]; !ok {
+		return fmt.Errorf("unknown layout %q", s.Layout)
+	}
+	if err := sim.CheckFood(s.Food); err != nil {
+		return err
+	}
+	if s.MaxPlayers < 1 || s.MaxPlayers > maxPlayers {
+		return fmt.Errorf("a room holds 1 to %d players", maxPlayers)
+	}
+	return nil
+}
+
+// Config returns the board the settings describe.
+func (s Settings) Config(seed int64) sim.Config {
+	return sim.Config{
+		Width:    s.Width,
+		Height:   s.Height,
+		Seed:     seed,
+		Wrap:     s.Walls == WallsWrap,
+		MoveTime: Speeds[s.Speed],
+		Mode:     s.Mode,
+		PowerUps: s.PowerUps,
+		Food:     s.Food,
+		Layout:   s.Layout,
+	}
+}
+
+// RoomInfo is what players in the lobby see of a room.
+type RoomInfo struct {
+	ID       int          `json:"id"`
+	Name     string       `json:"name"`
+	Settings Settings     `json:"settings"`
+	Players  []PlayerInfo `json:"players,omitempty"`
+	Started  bool         `json:"started,omitempty"`
+}
+
+// PlayerInfo is a player in a room.
+type PlayerInfo struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Ready bool   `json:"ready,omitempty"`
+}
+
+var (
+	errNoRoom   = errors.New("no such room")
+	errRoomFull = errors.New("room is full")
+	errTooMany  = errors.New("too many rooms")
+	errStarted  = errors.New("the game has started")
+)
+
+// room is one board on the server. It starts once everybody in it is ready.
+// A classic game then keeps going for as long as anyone is in the room, a
+// battle royale until it has a winner.
+type room struct {
+	id       int
+	name     string
+	settings Settings
+	players  []*player
+	ready    map[int]bool
+
+	world      *sim.World
+	overFor    int
+	spectators *Stream
+}
+
+func (r *room) info() RoomInfo {
+	ri := RoomInfo{
+		ID:       r.id,
+		Name:     r.name,
+		Settings: r.settings,
+		Started:  r.world != nil,
+	}
+	for _, p := range r.players {
+		ri.Players = append(ri.Players, PlayerInfo{ID: p.id, Name: p.name, Ready: r.ready[p.id]})
+	}
+	return ri
+}
+
+func (r *room) allReady() bool {
+	if len(r.players) == 0 {
+		return false
+	}
+	for _, p := range r.players {
+		if !r.ready[p.id] {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *room) broadcast(m *Message) {
+	for _, p := range r.players {
+		p.send(m)
+	}
+}
@@ -0,0 +1,358 @@
+package netplay
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"ebiten/Snake/sim"
+)
+
+const (
+	// MaxRollback is how many frames a peer runs ahead of the slowest
+	// other peer before it waits for it.
+	MaxRollback = 30
+
+	// HashInterval is how often, in frames, peers compare their worlds.
+	HashInterval = 60
+
+	dialTimeout = 30 * time.Second
+)
+
+// Peer plays one board together with other peers without a server. Peers
+// only send each other their inputs; each of them runs the same
+// deterministic sim.World with them.
+//
+// A local input is scheduled Delay frames ahead so that it usually reaches
+// the others in time. Until a peer's input for a frame is known it is
+// assumed to be empty, and when it turns out not to be the world is rolled
+// back to that frame and run forward again. Every HashInterval frames the
+// peers send each other a hash of their world to notice if they disagree.
+type Peer struct {
+	Index int
+	Delay int
+
+	conns   []Conn
+	world   *sim.World
+	history map[int]*sim.World  // the world before frame f ran
+	inputs  map[int][]sim.Input // the inputs of frame f by peer index
+	known   []int               // the last frame with a known input by peer index
+	pending sim.Input           // local input held back while waiting
+	hashed  int                 // the last frame whose hash is settled
+	hashes  map[int]uint64      // our hash after frame f
+	theirs  map[int][]Message   // their hashes we can't check yet
+
+	mu       sync.Mutex
+	incoming []Message
+	err      error
+}
+
+// NewPeer connects to all the other peers. addrs lists every peer's address
+// in the same order on each of them and index says which one is ours. All
+// peers play with the seed of the first one.
+func NewPeer(index int, addrs []string, delay int, cfg sim.Config) (*Peer, error) {
+	if index < 0 || index >= len(addrs) {
+		return nil, fmt.Errorf("peer index %d out of range", index)
+	}
+	p := &Peer{
+		Index:   index,
+		Delay:   delay,
+		conns:   make([]Conn, len(addrs)),
+		history: map[int]*sim.World{},
+		inputs:  map[int][]sim.Input{},
+		known:   make([]int, len(addrs)),
+		hashed:  -1,
+		hashes:  map[int]uint64{},
+		theirs:  map[int][]Message{},
+	}
+	seed, err := p.connect(addrs, cfg.Seed)
+	if err != nil {
+		p.Close()
+		return nil, err
+	}
+	cfg.Seed = seed
+
+	p.world = sim.NewWorld(cfg)
+	for i := range addrs {
+		p.world.AddSnake(i + 1)
+		p.known[i] = delay - 1
+	}
+	for i, c := range p.conns {
+		if c != nil {
+			go p.read(i, c)
+		}
+	}
+	return p, nil
+}
+
+// connect dials the peers before ours in addrs and waits for the ones after
+// it, then swaps hellos with everyone. It returns the first peer's seed.
+func (p *Peer) connect(addrs []string, seed int64) (int64, error) {
+	hello := &Message{Type: MsgPeerHello, ID: p.Index, Seed: seed}
+	for i := 0; i < p.Index; i++ {
+		c, err := dialRetry(addrs[i])
+		if err != nil {
+			return 0, err
+		}
+		if err := c.Send(hello); err != nil {
+			c.Close()
+			return 0, err
+		}
+		var m Message
+		if err := c.Recv(&m); err != nil {
+			c.Close()
+			return 0, err
+		}
+		if m.Type != MsgPeerHello || m.ID != i {
+			c.Close()
+			return 0, fmt.Errorf("%s is not peer %d", addrs[i], i)
+		}
+		p.conns[i] = c
+		if i == 0 {
+			seed = m.Seed
+		}
+	}
+
+	if p.Index == len(addrs)-1 {
+		return seed, nil
+	}
+	l, err := net.Listen("tcp", addrs[p.Index])
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	for waiting := len(addrs) - 1 - p.Index; waiting > 0; {
+		nc, err := l.Accept()
+		if err != nil {
+			return 0, err
+		}
+		c := NewTCPConn(nc)
+		var m Message
+		if err := c.Recv(&m); err != nil {
+			c.Close()
+			continue
+		}
+		if m.Type != MsgPeerHello || m.ID <= p.Index || m.ID >= len(addrs) || p.conns[m.ID] != nil {
+			c.Close()
+			continue
+		}
+		if err := c.Send(hello); err != nil {
+			c.Close()
+			return 0, err
+		}
+		p.conns[m.ID] = c
+		waiting--
+	}
+	return seed, nil
+}
+
+func dialRetry(addr string) (Conn, error) {
+	deadline := time.Now().Add(dialTimeout)
+	for {
+		c, err := net.Dial("tcp", addr)
+		if err == nil {
+			return NewTCPConn(c), nil
+		}
+		if time.Now().After(deadline) {
+			return nil, err
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+func (p *Peer) read(index int, c Conn) {
+	for {
+		var m Message
+		if err := c.Recv(&m); err != nil {
+			p.fail(fmt.Errorf("peer %d: %v", index, err))
+			return
+		}
+		m.ID = index
+		p.mu.Lock()
+		p.incoming = append(p.incoming, m)
+		p.mu.Unlock()
+	}
+}
+
+func (p *Peer) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err == nil {
+		p.err = err
+	}
+}
+
+func (p *Peer) broadcast(m *Message) {
+	for _, c := range p.conns {
+		if c == nil {
+			continue
+		}
+		if err := c.Send(m); err != nil {
+			p.fail(err)
+		}
+	}
+}
+
+func (p *Peer) input(frame, index int) sim.Input {
+	if in, ok := p.inputs[frame]; ok {
+		return in[index]
+	}
+	return sim.Input{}
+}
+
+func (p *Peer) setInput(frame, index int, in sim.Input) {
+	if _, ok := p.inputs[frame]; !ok {
+		p.inputs[frame] = make([]sim.Input, len(p.conns))
+	}
+	p.inputs[frame][index] = in
+}
+
+// confirmed returns the last frame for which every input is known.
+func (p *Peer) confirmed() int {
+	c := p.known[0]
+	for _, k := range p.known[1:] {
+		if k < c {
+			c = k
+		}
+	}
+	return c
+}
+
+func (p *Peer) step() {
+	f := p.world.Timer
+	p.history[f] = p.world.Clone()
+	for i := range p.conns {
+		p.world.Apply(i+1, p.input(f, i))
+	}
+	p.world.Update()
+}
+
+// Update is called once per frame with the local player's input and
+// returns the board to draw.
+func (p *Peer) Update(local sim.Input) (*sim.World, error) {
+	p.mu.Lock()
+	msgs := p.incoming
+	p.incoming = nil
+	err := p.err
+	p.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	rollback := -1
+	for i := range msgs {
+		m := &msgs[i]
+		switch m.Type {
+		case MsgPeerInput:
+			if m.Input == nil {
+				continue
+			}
+			p.setInput(m.Frame, m.ID, *m.Input)
+			if m.Frame > p.known[m.ID] {
+				p.known[m.ID] = m.Frame
+			}
+			if m.Frame < p.world.Timer && *m.Input != (sim.Input{}) && (rollback < 0 || m.Frame < rollback) {
+				rollback = m.Frame
+			}
+		case MsgPeerHash:
+			p.theirs[m.Frame] = append(p.theirs[m.Frame], *m)
+		}
+	}
+	if rollback >= 0 {
+		now := p.world.Timer
+		p.world = p.history[rollback].Clone()
+		for p.world.Timer < now {
+			p.step()
+		}
+	}
+
+	if local != (sim.Input{}) {
+		p.pending = local
+	}
+	if p.world.Timer-p.confirmed() <= MaxRollback {
+		f := p.world.Timer + p.Delay
+		in := p.pending
+		p.pending = sim.Input{}
+		p.setInput(f, p.Index, in)
+		p.known[p.Index] = f
+		p.broadcast(&Message{Type: MsgPeerInput, Frame: f, Input: &in})
+		p.step()
+	}
+
+	if err := p.checkHashes(); err != nil {
+		p.fail(err)
+		return nil, err
+	}
+	p.forget()
+	return p.world, nil
+}
+
+// checkHashes hashes the frames that can no longer change and compares
+// them with what the other peers got.
+func (p *Peer) checkHashes() error {
+	last := p.confirmed()
+	if last >= p.world.Timer {
+		last = p.world.Timer - 1
+	}
+	for f := p.hashed + 1; f <= last; f++ {
+		if f%HashInterval != 0 {
+			continue
+		}
+		after := p.world
+		if w, ok := p.history[f+1]; ok {
+			after = w
+		}
+		h := after.Hash()
+		p.hashes[f] = h
+		p.broadcast(&Message{Type: MsgPeerHash, Frame: f, Hash: h})
+	}
+	if last > p.hashed {
+		p.hashed = last
+	}
+	for f, ms := range p.theirs {
+		h, ok := p.hashes[f]
+		if !ok {
+			continue
+		}
+		for _, m := range ms {
+			if m.Hash != h {
+				return fmt.Errorf("out of sync with peer %d at frame %d", m.ID, f)
+			}
+		}
+		delete(p.theirs, f)
+	}
+	return nil
+}
+
+// forget drops what no rollback or hash check can need any more.
+func (p *Peer) forget() {
+	oldest := p.hashed
+	if c := p.confirmed(); c < oldest {
+		oldest = c
+	}
+	for f := range p.history {
+		if f < oldest {
+			delete(p.history, f)
+		}
+	}
+	for f := range p.inputs {
+		if f < oldest {
+			delete(p.inputs, f)
+		}
+	}
+	for f := range p.hashes {
+		if f < oldest-HashInterval {
+			delete(p.hashes, f)
+		}
+	}
+}
+
+func (p *Peer) Close() error {
+	for _, c := range p.conns {
+		if c != nil {
+			c.Close()
+		}
+	}
+	return nil
+}
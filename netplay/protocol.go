@@ -0,0 +1,57 @@
+// Package netplay runs the game over the network. The server keeps a lobby of
+// rooms, each of which owns the only real sim.World of its game. Clients send
+// it direction inputs and draw the snapshots it sends back.
+package netplay
+
+import (
+	"ebiten/Snake/sim"
+)
+
+// Message types. Every message is one JSON object.
+const (
+	MsgHello    = "hello"    // client: first message, carries Name
+	MsgWelcome  = "welcome"  // server: carries the player's ID, Rooms follow
+	MsgError    = "error"    // server: Error, the connection is closed after it
+	MsgRefused  = "refused"  // server: Error, the last request was turned down
+	MsgList     = "list"     // client: asks for Rooms
+	MsgRooms    = "rooms"    // server: Rooms, sent to players not in a room when they change
+	MsgCreate   = "create"   // client: Room with Name and Settings, joins it too
+	MsgJoin     = "join"     // client: ID of the room
+	MsgLeave    = "leave"    // client: back to the lobby
+	MsgReady    = "ready"    // client: Ready or not, the room starts when all are
+	MsgRoom     = "room"     // server: Room, whenever the player's room changes
+	MsgStart    = "start"    // server: Room and World, the game has started
+	MsgInput    = "input"    // client: Dir with an increasing Seq
+	MsgToggleAI = "ai"       // client: let the AI steer this player's snake
+	MsgReset    = "reset"    // client: respawn this player's snake
+	MsgSnapshot = "snapshot" // server: World after a move, Ack is the last Seq applied
+
+	// Spectators only ever send MsgFollow. They get a MsgSnapshot every
+	// tick with ID set to the snake they follow and Path to its AI's plan.
+	MsgFollow = "follow" // spectator: ID of the snake to follow, 0 for none
+
+	// Lockstep peers talk to each other directly, see Peer.
+	MsgPeerHello = "peer" // peer: its index as ID and its Seed
+	MsgPeerInput = "tick" // peer: its Input for Frame
+	MsgPeerHash  = "hash" // peer: Hash of the world after Frame
+)
+
+// Message is what travels between the server and the clients.
+type Message struct {
+	Type  string         `json:"type"`
+	Name  string         `json:"name,omitempty"`
+	ID    int            `json:"id,omitempty"`
+	Seq   int            `json:"seq,omitempty"`
+	Dir   int            `json:"dir,omitempty"`
+	Ack   int            `json:"ack,omitempty"`
+	World *sim.World     `json:"world,omitempty"`
+	Path  []sim.Position `json:"path,omitempty"`
+	Seed  int64          `json:"seed,omitempty"`
+	Frame int            `json:"frame,omitempty"`
+	Input *sim.Input     `json:"input,omitempty"`
+	Hash  uint64         `json:"hash,omitempty"`
+	Room  *RoomInfo      `json:"room,omitempty"`
+	Rooms []RoomInfo     `json:"rooms,omitempty"`
+	Ready bool           `json:"ready,omitempty"`
+	Error string         `json:"error,omitempty"`
+}
@@ -0,0 +1,419 @@
+package netplay
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"ebiten/Snake/sim"
+
+	"github.com/gorilla/websocket"
+)
+
+// TicksPerSecond matches ebiten's default TPS so that moveTime means the same
+// on the server as in the window.
+const TicksPerSecond = 60
+
+// roundBreak is how long the end of a game stays on screen before its room
+// goes back to ready-up, in ticks.
+const roundBreak = 5 * TicksPerSecond
+
+var errServerFull = errors.New("server is full")
+
+// Server runs a lobby of rooms, each with its own board. Players connect,
+// create or join a room and play once everyone in it is ready.
+type Server struct {
+	// MaxPlayers caps the players of a room, MaxRooms the rooms and
+	// MaxRooms*MaxPlayers the connections.
+	MaxPlayers int
+	MaxRooms   int
+
+	mu       sync.Mutex
+	players  map[int]*player
+	rooms    map[int]*room
+	nextID   int
+	nextRoom int
+
+	upgrader websocket.Upgrader
+}
+
+type player struct {
+	id   int
+	name string
+	conn Conn
+	ack  int
+	room *room
+	out  chan *Message
+}
+
+func NewServer(maxPlayers, maxRooms int) *Server {
+	return &Server{
+		MaxPlayers: maxPlayers,
+		MaxRooms:   maxRooms,
+		players:    map[int]*player{},
+		rooms:      map[int]*room{},
+		nextID:     1,
+		nextRoom:   1,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+// Run advances the boards of all started rooms forever.
+func (s *Server) Run() {
+	t := time.NewTicker(time.Second / TicksPerSecond)
+	defer t.Stop()
+	for range t.C {
+		s.tick()
+	}
+}
+
+func (s *Server) tick() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, r := range s.rooms {
+		if r.world == nil {
+			continue
+		}
+		if r.world.Over {
+			// Show the result for a while, then go back to ready-up.
+			r.overFor++
+			if r.overFor == roundBreak {
+				s.stop(r)
+			}
+			continue
+		}
+		if !r.world.Update() {
+			continue
+		}
+		r.spectators.Publish(r.world)
+		snap := r.world.Clone()
+		for _, p := range r.players {
+			p.sendSnapshot(&Message{Type: MsgSnapshot, Ack: p.ack, World: snap})
+		}
+	}
+}
+
+// Serve accepts TCP clients on l.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.handle(NewTCPConn(c))
+	}
+}
+
+// ServeHTTP accepts WebSocket clients.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	go s.handle(NewWebSocketConn(c))
+}
+
+// Spectators returns the handler for read-only WebSocket spectators. The
+// room to watch is given as ?room=ID, the first started one otherwise.
+func (s *Server) Spectators() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		rm := s.spectatedRoom(r.URL.Query().Get("room"))
+		s.mu.Unlock()
+		if rm == nil {
+			http.Error(w, errNoRoom.Error(), http.StatusNotFound)
+			return
+		}
+		rm.spectators.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) spectatedRoom(id string) *room {
+	if id != "" {
+		n, err := strconv.Atoi(id)
+		if err != nil {
+			return nil
+		}
+		return s.rooms[n]
+	}
+	for _, ri := range s.roomList() {
+		if ri.Started {
+			return s.rooms[ri.ID]
+		}
+	}
+	return nil
+}
+
+func (s *Server) handle(c Conn) {
+	defer c.Close()
+
+	var hello Message
+	if err := c.Recv(&hello); err != nil {
+		return
+	}
+	if hello.Type != MsgHello {
+		c.Send(&Message{Type: MsgError, Error: "expected hello"})
+		return
+	}
+	p, err := s.join(c, hello.Name)
+	if err != nil {
+		c.Send(&Message{Type: MsgError, Error: err.Error()})
+		return
+	}
+	defer s.leave(p)
+	go p.write()
+
+	for {
+		var m Message
+		if err := c.Recv(&m); err != nil {
+			return
+		}
+		s.apply(p, &m)
+	}
+}
+
+func (s *Server) join(c Conn, name string) (*player, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.players) >= s.MaxPlayers*s.MaxRooms {
+		return nil, errServerFull
+	}
+	p := &player{
+		id:   s.nextID,
+		name: name,
+		conn: c,
+		out:  make(chan *Message, 64),
+	}
+	s.nextID++
+	s.players[p.id] = p
+	p.send(&Message{Type: MsgWelcome, ID: p.id})
+	p.send(&Message{Type: MsgRooms, Rooms: s.roomList()})
+	log.Printf("player %d (%s) connected", p.id, name)
+	return p, nil
+}
+
+func (s *Server) leave(p *player) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.exit(p)
+	delete(s.players, p.id)
+	close(p.out)
+	log.Printf("player %d disconnected", p.id)
+}
+
+func (s *Server) apply(p *player, m *Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch m.Type {
+	case MsgList:
+		p.send(&Message{Type: MsgRooms, Rooms: s.roomList()})
+	case MsgCreate:
+		s.create(p, m.Room)
+	case MsgJoin:
+		r, ok := s.rooms[m.ID]
+		if !ok {
+			p.send(&Message{Type: MsgRefused, Error: errNoRoom.Error()})
+			return
+		}
+		s.enter(p, r)
+	case MsgLeave:
+		s.exit(p)
+		p.send(&Message{Type: MsgRooms, Rooms: s.roomList()})
+	case MsgReady:
+		r := p.room
+		if r == nil || r.world != nil {
+			return
+		}
+		r.ready[p.id] = m.Ready
+		r.broadcast(&Message{Type: MsgRoom, Room: roomInfo(r)})
+		if r.allReady() {
+			s.start(r)
+		}
+	}
+
+	if p.room == nil || p.room.world == nil {
+		return
+	}
+	w := p.room.world
+	switch m.Type {
+	case MsgInput:
+		if m.Seq <= p.ack {
+			return
+		}
+		p.ack = m.Seq
+		w.SetDirection(p.id, m.Dir)
+	case MsgToggleAI:
+		w.Apply(p.id, sim.Input{ToggleAI: true})
+	case MsgReset:
+		w.Apply(p.id, sim.Input{Reset: true})
+	}
+}
+
+func (s *Server) create(p *player, req *RoomInfo) {
+	if len(s.rooms) >= s.MaxRooms {
+		p.send(&Message{Type: MsgRefused, Error: errTooMany.Error()})
+		return
+	}
+	r := &room{
+		id:         s.nextRoom,
+		settings:   DefaultSettings,
+		ready:      map[int]bool{},
+		spectators: NewStream(),
+	}
+	if req != nil {
+		r.name = req.Name
+		r.settings = req.Settings
+	}
+	if r.settings.MaxPlayers > s.MaxPlayers {
+		r.settings.MaxPlayers = s.MaxPlayers
+	}
+	if err := r.settings.validate(s.MaxPlayers); err != nil {
+		p.send(&Message{Type: MsgRefused, Error: err.Error()})
+		return
+	}
+	if r.name == "" {
+		r.name = fmt.Sprintf("%s's room", p.name)
+	}
+	s.nextRoom++
+	s.rooms[r.id] = r
+	log.Printf("player %d created room %d (%s)", p.id, r.id, r.name)
+	s.enter(p, r)
+}
+
+func (s *Server) enter(p *player, r *room) {
+	if p.room == r {
+		return
+	}
+	if len(r.players) >= r.settings.MaxPlayers {
+		p.send(&Message{Type: MsgRefused, Error: errRoomFull.Error()})
+		return
+	}
+	if r.world != nil && r.settings.Mode != sim.ModeClassic {
+		p.send(&Message{Type: MsgRefused, Error: errStarted.Error()})
+		return
+	}
+	s.exit(p)
+	p.room = r
+	p.ack = 0
+	r.players = append(r.players, p)
+	if r.world != nil {
+		r.world.AddSnake(p.id).Name = p.name
+		p.send(&Message{Type: MsgStart, Room: roomInfo(r), World: r.world.Clone()})
+	}
+	r.broadcast(&Message{Type: MsgRoom, Room: roomInfo(r)})
+	s.lobbyChanged()
+}
+
+// exit takes the player out of its room, closing the room when it was the
+// last one in it.
+func (s *Server) exit(p *player) {
+	r := p.room
+	if r == nil {
+		return
+	}
+	p.room = nil
+	for i, o := range r.players {
+		if o == p {
+			r.players = append(r.players[:i], r.players[i+1:]...)
+			break
+		}
+	}
+	delete(r.ready, p.id)
+	if r.world != nil {
+		r.world.Leave(p.id)
+	}
+	if len(r.players) == 0 {
+		delete(s.rooms, r.id)
+		r.spectators.Close()
+		log.Printf("room %d closed", r.id)
+	} else {
+		r.broadcast(&Message{Type: MsgRoom, Room: roomInfo(r)})
+		if r.world == nil && r.allReady() {
+			s.start(r)
+		}
+	}
+	s.lobbyChanged()
+}
+
+func (s *Server) start(r *room) {
+	r.world = sim.NewWorld(r.settings.Config(time.Now().UnixNano()))
+	for _, p := range r.players {
+		r.world.AddSnake(p.id).Name = p.name
+	}
+	r.broadcast(&Message{Type: MsgStart, Room: roomInfo(r), World: r.world.Clone()})
+	log.Printf("room %d started with %d players", r.id, len(r.players))
+	s.lobbyChanged()
+}
+
+// stop ends a room's game and has its players ready up again.
+func (s *Server) stop(r *room) {
+	r.world = nil
+	r.overFor = 0
+	r.ready = map[int]bool{}
+	r.broadcast(&Message{Type: MsgRoom, Room: roomInfo(r)})
+	log.Printf("room %d finished", r.id)
+	s.lobbyChanged()
+}
+
+// lobbyChanged tells everyone who is not in a room about the rooms.
+func (s *Server) lobbyChanged() {
+	var rooms []RoomInfo
+	for _, p := range s.players {
+		if p.room != nil {
+			continue
+		}
+		if rooms == nil {
+			rooms = s.roomList()
+		}
+		p.send(&Message{Type: MsgRooms, Rooms: rooms})
+	}
+}
+
+func (s *Server) roomList() []RoomInfo {
+	rooms := []RoomInfo{}
+	for _, r := range s.rooms {
+		rooms = append(rooms, r.info())
+	}
+	sort.Slice(rooms, func(i, j int) bool { return rooms[i].ID < rooms[j].ID })
+	return rooms
+}
+
+func roomInfo(r *room) *RoomInfo {
+	ri := r.info()
+	return &ri
+}
+
+// send queues m for the player. A client that stops reading altogether
+// loses messages rather than stalling the server.
+func (p *player) send(m *Message) {
+	select {
+	case p.out <- m:
+	default:
+	}
+}
+
+// sendSnapshot queues a snapshot unless the player is falling behind. A
+// client that cannot keep up misses snapshots, which leaves room for the
+// messages it must not miss.
+func (p *player) sendSnapshot(m *Message) {
+	if len(p.out) < cap(p.out)/2 {
+		p.send(m)
+	}
+}
+
+func (p *player) write() {
+	for m := range p.out {
+		if err := p.conn.Send(m); err != nil {
+			p.conn.Close()
+			return
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package netplay
+
+import (
+	"net"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ebiten/Snake/sim"
+)
+
+// waitFor polls cond until it holds, failing the test if it doesn't within a
+// few seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// serve runs srv over WebSocket and returns its address. Nothing ticks the
+// boards; the tests do that themselves.
+func serve(srv *Server) (addr string, stop func()) {
+	ts := httptest.NewServer(srv)
+	return "ws://" + strings.TrimPrefix(ts.URL, "http://"), ts.Close
+}
+
+func connect(t *testing.T, addr, name string) *Client {
+	t.Helper()
+	c, err := Connect(addr, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// world returns a copy of the board of the room with the given id, nil if
+// it hasn't started.
+func (s *Server) world(id int) *sim.World {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r, ok := s.rooms[id]; ok && r.world != nil {
+		return r.world.Clone()
+	}
+	return nil
+}
+
+func (s *Server) ticks(n int) {
+	for i := 0; i < n; i++ {
+		s.tick()
+	}
+}
+
+func TestRoyale(t *testing.T) {
+	srv := NewServer(3, 2)
+	addr, stop := serve(srv)
+	defer stop()
+
+	host := connect(t, addr, "host")
+	defer host.Close()
+	settings := DefaultSettings
+	settings.Mode = sim.ModeRoyale
+	settings.Width, settings.Height = 32, 24
+	settings.MaxPlayers = 3
+	if err := host.Create("arena", settings); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the room", func() bool { r := host.Room(); return r != nil && r.ID != 0 })
+	id := host.Room().ID
+
+	var guests []*Client
+	for _, name := range []string{"left", "quit"} {
+		c := connect(t, addr, name)
+		defer c.Close()
+		waitFor(t, name+" to see the room", func() bool { return len(c.Rooms()) == 1 })
+		if err := c.Join(id); err != nil {
+			t.Fatal(err)
+		}
+		guests = append(guests, c)
+	}
+	waitFor(t, "everyone in the room", func() bool { return len(host.Room().Players) == 3 })
+
+	for _, c := range append([]*Client{host}, guests...) {
+		if err := c.Ready(true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range append([]*Client{host}, guests...) {
+		waitFor(t, "the game to start", c.Playing)
+	}
+	if w := srv.world(id); w == nil || len(w.Snakes) != 3 {
+		t.Fatal("the room started without three snakes")
+	}
+
+	// The server acknowledges the turn with the snapshot that follows it.
+	if err := host.Turn(sim.DirRight); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the turn", func() bool {
+		w := srv.world(id)
+		return w.Snake(host.ID).Dir == sim.DirRight
+	})
+	srv.ticks(2 * Speeds[settings.Speed])
+	waitFor(t, "the acknowledgement", func() bool { return host.Pending() == 0 })
+	w, err := host.Update()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w == nil || w.Snake(host.ID).Dir != sim.DirRight {
+		t.Fatal("the client doesn't show its snake turning")
+	}
+
+	// One guest goes back to the lobby, the other hangs up. The host has
+	// the board to itself and wins.
+	if err := guests[0].Leave(); err != nil {
+		t.Fatal(err)
+	}
+	guests[1].Close()
+	waitFor(t, "the guests to go", func() bool { return srv.world(id).Alive() == 1 })
+	waitFor(t, "the lobby", func() bool { return len(guests[0].Rooms()) == 1 && guests[0].Rooms()[0].Started })
+	srv.ticks(1)
+	if w := srv.world(id); !w.Over || w.Winner != host.ID {
+		t.Fatalf("over %v, winner %d; want the host, %d, to win", w.Over, w.Winner, host.ID)
+	}
+
+	// After the break the room readies up again.
+	srv.ticks(roundBreak)
+	waitFor(t, "ready-up", func() bool { return !host.Playing() && !host.Room().Started })
+}
+
+func TestLobby(t *testing.T) {
+	srv := NewServer(2, 2)
+	addr, stop := serve(srv)
+	defer stop()
+
+	a := connect(t, addr, "a")
+	defer a.Close()
+	b := connect(t, addr, "b")
+	defer b.Close()
+	c := connect(t, addr, "c")
+	defer c.Close()
+
+	if err := a.Join(7); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "a refusal", func() bool { return a.Refused() == errNoRoom.Error() })
+	if a.Room() != nil {
+		t.Fatal("a refused join left the player in a room")
+	}
+
+	bad := DefaultSettings
+	bad.Width = MinBoardWidth - 1
+	if err := a.Create("", bad); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "a refusal", func() bool { return strings.HasPrefix(a.Refused(), "board must be") })
+
+	if err := a.Create("", DefaultSettings); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the room", func() bool { r := a.Room(); return r != nil && r.ID != 0 })
+	if name := a.Room().Name; name != "a's room" {
+		t.Errorf("room named %q, want a's room", name)
+	}
+	if err := b.Create("", DefaultSettings); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the second room", func() bool { r := b.Room(); return r != nil && r.ID != 0 })
+	if err := c.Create("", DefaultSettings); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "a refusal", func() bool { return c.Refused() == errTooMany.Error() })
+	if err := b.Leave(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the second room to close", func() bool { return len(c.Rooms()) == 1 })
+
+	// A classic game takes players in as it goes, as many as fit.
+	id := a.Room().ID
+	if err := a.Ready(true); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the game to start", a.Playing)
+	if err := b.Join(id); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "b to join the game", b.Playing)
+	if w := srv.world(id); w.Snake(b.ID) == nil {
+		t.Fatal("the board has no snake for b")
+	}
+	if err := c.Join(id); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "a refusal", func() bool { return c.Refused() == errRoomFull.Error() })
+
+	// The room closes with the last player in it.
+	a.Close()
+	if err := b.Leave(); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, "the room to close", func() bool { return len(c.Rooms()) == 0 })
+}
+
+// freeAddrs returns n local addresses nothing listens on.
+func freeAddrs(t *testing.T, n int) []string {
+	var addrs []string
+	for i := 0; i < n; i++ {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		addrs = append(addrs, l.Addr().String())
+		l.Close()
+	}
+	return addrs
+}
+
+func TestLockstep(t *testing.T) {
+	const (
+		n      = 3
+		frames = 4 * HashInterval
+	)
+	addrs := freeAddrs(t, n)
+	cfg := sim.Config{Width: 32, Height: 24, Seed: time.Now().UnixNano()}
+
+	peers := make([]*Peer, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range peers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Every peer brings a seed of its own; they play the first's.
+			c := cfg
+			c.Seed += int64(i)
+			peers[i], errs[i] = NewPeer(i, addrs, 2, c)
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("peer %d: %v", i, err)
+		}
+		defer peers[i].Close()
+	}
+
+	// Each peer turns its snake now and then, on frames of its own.
+	turns := []int{sim.DirRight, sim.DirDown, sim.DirLeft, sim.DirUp}
+	deadline := time.Now().Add(10 * time.Second)
+	for done := false; !done; {
+		if time.Now().After(deadline) {
+			t.Fatal("the peers never got that far")
+		}
+		done = true
+		for i, p := range peers {
+			var in sim.Input
+			if f := p.world.Timer; f%(7+i) == 0 {
+				in.Dir = turns[(f/(7+i)+i)%len(turns)]
+			}
+			if _, err := p.Update(in); err != nil {
+				t.Fatalf("peer %d: %v", i, err)
+			}
+			done = done && p.hashed >= frames
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// The peers compared their hashes along the way; check them once more
+	// for every frame they all still remember.
+	checked := 0
+	for f, h := range peers[0].hashes {
+		same := true
+		for _, p := range peers[1:] {
+			if theirs, ok := p.hashes[f]; ok {
+				same = same && theirs == h
+				checked++
+			}
+		}
+		if !same {
+			t.Errorf("the peers disagree at frame %d", f)
+		}
+	}
+	if checked == 0 {
+		t.Error("the peers have no frame in common to check")
+	}
+	for i, p := range peers {
+		if p.world.Timer < frames {
+			t.Errorf("peer %d is at frame %d", i, p.world.Timer)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package netplay
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+
+	"ebiten/Snake/sim"
+
+	"github.com/gorilla/websocket"
+)
+
+// pathLength is how far ahead a followed snake's AI plan is sent.
+const pathLength = 64
+
+// Stream sends a board to read-only spectators over WebSocket. Whoever owns
+// the board calls Publish after every move, be it the server or a local game.
+type Stream struct {
+	mu       sync.Mutex
+	watchers map[*watcher]struct{}
+	upgrader websocket.Upgrader
+}
+
+type watcher struct {
+	conn   Conn
+	follow int
+	out    chan *Message
+}
+
+func NewStream() *Stream {
+	return &Stream{
+		watchers: map[*watcher]struct{}{},
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+// ServeHTTP accepts a spectator.
+func (st *Stream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c, err := st.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	go st.handle(NewWebSocketConn(c))
+}
+
+func (st *Stream) handle(c Conn) {
+	defer c.Close()
+	wt := &watcher{conn: c, out: make(chan *Message, 16)}
+	st.mu.Lock()
+	st.watchers[wt] = struct{}{}
+	st.mu.Unlock()
+	defer func() {
+		st.mu.Lock()
+		delete(st.watchers, wt)
+		close(wt.out)
+		st.mu.Unlock()
+	}()
+	go func() {
+		for m := range wt.out {
+			if err := c.Send(m); err != nil {
+				c.Close()
+				return
+			}
+		}
+	}()
+
+	for {
+		var m Message
+		if err := c.Recv(&m); err != nil {
+			return
+		}
+		if m.Type == MsgFollow {
+			st.mu.Lock()
+			wt.follow = m.ID
+			st.mu.Unlock()
+		}
+	}
+}
+
+// Close disconnects all spectators.
+func (st *Stream) Close() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	for wt := range st.watchers {
+		wt.conn.Close()
+	}
+}
+
+// Publish sends w to every spectator. It must not run concurrently with
+// changes to w.
+func (st *Stream) Publish(w *sim.World) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if len(st.watchers) == 0 {
+		return
+	}
+	snap := w.Clone()
+	paths := map[int][]sim.Position{}
+	for wt := range st.watchers {
+		path, ok := paths[wt.follow]
+		if !ok {
+			if s := snap.Snake(wt.follow); s != nil {
+				path = snap.PlannedPath(s, pathLength)
+			}
+			paths[wt.follow] = path
+		}
+		select {
+		case wt.out <- &Message{Type: MsgSnapshot, ID: wt.follow, World: snap, Path: path}:
+		default:
+		}
+	}
+}
+
+// Spectator watches a Stream.
+type Spectator struct {
+	conn Conn
+
+	mu   sync.Mutex
+	last *Message
+	err  error
+}
+
+// Watch connects to the stream at addr, a ws:// URL, following the snake
+// with the given id.
+func Watch(addr string, follow int) (*Spectator, error) {
+	conn, err := Dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	sp := &Spectator{conn: conn}
+	if err := sp.Follow(follow); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	go sp.read()
+	return sp, nil
+}
+
+func (sp *Spectator) read() {
+	for {
+		m := &Message{}
+		if err := sp.conn.Recv(m); err != nil {
+			sp.mu.Lock()
+			sp.err = err
+			sp.mu.Unlock()
+			return
+		}
+		if m.Type == MsgSnapshot && m.World != nil {
+			sp.mu.Lock()
+			sp.last = m
+			sp.mu.Unlock()
+		}
+	}
+}
+
+// Follow switches to another snake.
+func (sp *Spectator) Follow(id int) error {
+	return sp.conn.Send(&Message{Type: MsgFollow, ID: id})
+}
+
+// ErrNoSnapshot is returned by Latest until the first board arrives.
+var ErrNoSnapshot = errors.New("no snapshot yet")
+
+// Latest returns the newest board and the followed snake's planned path.
+func (sp *Spectator) Latest() (*sim.World, []sim.Position, error) {
+	sp.mu.Lock()
+	defer sp.mu.Unlock()
+	if sp.err != nil {
+		return nil, nil, sp.err
+	}
+	if sp.last == nil {
+		return nil, nil, ErrNoSnapshot
+	}
+	return sp.last.World, sp.last.Path, nil
+}
+
+func (sp *Spectator) Close() error {
+	return sp.conn.Close()
+}
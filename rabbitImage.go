@@ -0,0 +1,4 @@
+package main
+
+// RabbitImage ...
+var RabbitImage = []byte{137, 80, 78, 71, 13, 10, 26, 10, 0, 0, 0, 13, 73, 72, 68, 82, 0, 0, 0, 10, 0, 0, 0, 12, 8, 6, 0, 0, 0, 91, 107, 44, 160, 0, 0, 0, 1, 115, 82, 71, 66, 0, 174, 206, 28, 233, 0, 0, 0, 9, 112, 72, 89, 115, 0, 0, 11, 19, 0, 0, 11, 19, 1, 0, 154, 156, 24, 0, 0, 1, 89, 105, 84, 88, 116, 88, 77, 76, 58, 99, 111, 109, 46, 97, 100, 111, 98, 101, 46, 120, 109, 112, 0, 0, 0, 0, 0, 60, 120, 58, 120, 109, 112, 109, 101, 116, 97, 32, 120, 109, 108, 110, 115, 58, 120, 61, 34, 97, 100, 111, 98, 101, 58, 110, 115, 58, 109, 101, 116, 97, 47, 34, 32, 120, 58, 120, 109, 112, 116, 107, 61, 34, 88, 77, 80, 32, 67, 111, 114, 101, 32, 53, 46, 52, 46, 48, 34, 62, 10, 32, 32, 32, 60, 114, 100, 102, 58, 82, 68, 70, 32, 120, 109, 108, 110, 115, 58, 114, 100, 102, 61, 34, 104, 116, 116, 112, 58, 47, 47, 119, 119, 119, 46, 119, 51, 46, 111, 114, 103, 47, 49, 57, 57, 57, 47, 48, 50, 47, 50, 50, 45, 114, 100, 102, 45, 115, 121, 110, 116, 97, 120, 45, 110, 115, 35, 34, 62, 10, 32, 32, 32, 32, 32, 32, 60, 114, 100, 102, 58, 68, 101, 115, 99, 114, 105, 112, 116, 105, 111, 110, 32, 114, 100, 102, 58, 97, 98, 111, 117, 116, 61, 34, 34, 10, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 32, 120, 109, 108, 110, 115, 58, 116, 105, 102, 102, 61, 34, 104, 116, 116, 112, 58, 47, 47, 110, 115, 46, 97, 100, 111, 98, 101, 46, 99, 111, 109, 47, 116, 105, 102, 102, 47, 49, 46, 48, 47, 34, 62, 10, 32, 32, 32, 32, 32, 32, 32, 32, 32, 60, 116, 105, 102, 102, 58, 79, 114, 105, 101, 110, 116, 97, 116, 105, 111, 110, 62, 49, 60, 47, 116, 105, 102, 102, 58, 79, 114, 105, 101, 110, 116, 97, 116, 105, 111, 110, 62, 10, 32, 32, 32, 32, 32, 32, 60, 47, 114, 100, 102, 58, 68, 101, 115, 99, 114, 105, 112, 116, 105, 111, 110, 62, 10, 32, 32, 32, 60, 47, 114, 100, 102, 58, 82, 68, 70, 62, 10, 60, 47, 120, 58, 120, 109, 112, 109, 101, 116, 97, 62, 10, 76, 194, 39, 89, 0, 0, 1, 141, 73, 68, 65, 84, 24, 25, 53, 144, 203, 78, 90, 81, 24, 133, 191, 179, 57, 61, 200, 61, 145, 67, 211, 64, 177, 19, 102, 164, 190, 65, 219, 40, 209, 23, 232, 160, 13, 157, 25, 125, 4, 19, 194, 59, 244, 57, 154, 146, 78, 234, 140, 154, 72, 212, 87, 144, 210, 75, 180, 167, 130, 29, 112, 41, 23, 207, 17, 80, 96, 119, 239, 157, 116, 13, 254, 228, 191, 100, 173, 245, 47, 171, 82, 169, 72, 20, 44, 203, 98, 62, 159, 227, 251, 62, 225, 112, 152, 233, 116, 138, 235, 186, 122, 101, 96, 235, 250, 255, 232, 89, 62, 79, 161, 80, 96, 60, 26, 145, 223, 216, 224, 243, 209, 17, 182, 227, 128, 148, 152, 195, 80, 40, 196, 237, 100, 194, 214, 246, 54, 207, 55, 55, 249, 115, 211, 33, 155, 123, 202, 249, 217, 25, 99, 63, 64, 8, 11, 161, 25, 133, 16, 252, 246, 60, 195, 172, 123, 71, 179, 40, 232, 131, 94, 175, 107, 246, 66, 203, 254, 29, 12, 80, 94, 201, 100, 92, 22, 139, 5, 110, 230, 49, 157, 235, 107, 246, 246, 15, 40, 191, 125, 195, 104, 56, 68, 12, 250, 125, 222, 149, 203, 108, 149, 74, 156, 212, 235, 116, 218, 109, 195, 118, 222, 56, 97, 54, 155, 81, 218, 217, 229, 213, 203, 23, 80, 57, 60, 148, 129, 239, 75, 141, 201, 120, 44, 123, 221, 174, 108, 28, 31, 203, 111, 173, 150, 153, 233, 210, 106, 54, 165, 88, 139, 172, 33, 212, 51, 26, 137, 100, 18, 75, 249, 125, 255, 122, 135, 118, 237, 35, 211, 187, 59, 51, 215, 63, 216, 125, 37, 173, 61, 102, 115, 57, 227, 47, 157, 78, 243, 161, 51, 97, 245, 240, 128, 163, 242, 212, 240, 188, 95, 138, 236, 145, 195, 167, 90, 13, 37, 143, 109, 155, 180, 136, 39, 18, 36, 215, 215, 209, 177, 253, 252, 241, 157, 122, 253, 11, 86, 181, 90, 149, 65, 16, 144, 140, 199, 41, 22, 139, 68, 35, 17, 158, 100, 115, 120, 87, 151, 12, 71, 67, 46, 154, 95, 137, 68, 163, 216, 203, 229, 146, 88, 44, 198, 189, 138, 165, 113, 122, 74, 84, 201, 233, 120, 180, 165, 251, 229, 130, 84, 42, 133, 92, 173, 248, 7, 1, 36, 173, 129, 203, 48, 36, 59, 0, 0, 0, 0, 73, 69, 78, 68, 174, 66, 96, 130}
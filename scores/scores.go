@@ -0,0 +1,175 @@
+// Package scores keeps the best results of the challenges, one table per
+// challenge, in a file in the player's home directory or in the browser.
+package scores
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"ebiten/Snake/sim"
+	"ebiten/Snake/storage"
+)
+
+// Size is how many entries a table keeps.
+const Size = 10
+
+// Entry is one result.
+type Entry struct {
+	Name  string `json:"name"`
+	Score int    `json:"score"`
+	Date  string `json:"date"`
+
+	// Replay is where the game can be watched again, if it can.
+	Replay string `json:"replay,omitempty"`
+}
+
+// Table is the best results of one challenge, best first.
+type Table struct {
+	LowerIsBetter bool    `json:"lowerIsBetter,omitempty"`
+	Entries       []Entry `json:"entries"`
+}
+
+// Book is all the tables.
+type Book struct {
+	Tables map[string]*Table `json:"tables"`
+
+	path string
+}
+
+// Path returns where the scores are kept, "" when there is no home
+// directory to keep them in.
+func Path() string {
+	return storage.Path("scores.json")
+}
+
+// Load reads the book at path. A book that does not exist yet is empty, and
+// so is one with no path, which is then never saved.
+func Load(path string) (*Book, error) {
+	b := &Book{Tables: map[string]*Table{}, path: path}
+	if path == "" {
+		return b, nil
+	}
+	data, err := storage.ReadFile(path)
+	if os.IsNotExist(err) {
+		return b, nil
+	}
+	if err != nil {
+		return b, err
+	}
+	if err := json.Unmarshal(data, b); err != nil {
+		return b, err
+	}
+	if b.Tables == nil {
+		b.Tables = map[string]*Table{}
+	}
+	return b, nil
+}
+
+// Save writes the book back to where it was loaded from.
+func (b *Book) Save() error {
+	if b.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile(b.path, data)
+}
+
+// Add enters a result into the named table and returns its place, counting
+// from 1, or 0 when it did not make the table. The date is today's unless the
+// entry has one. The replays of a result that did not make the table and of
+// one it pushed off are deleted.
+func (b *Book) Add(table string, lowerIsBetter bool, e Entry) int {
+	t := b.Tables[table]
+	if t == nil {
+		t = &Table{LowerIsBetter: lowerIsBetter}
+		b.Tables[table] = t
+	}
+	if e.Date == "" {
+		e.Date = time.Now().Format("2006-01-02")
+	}
+	i := t.place(e.Score)
+	if i >= Size {
+		removeReplay(e.Replay)
+		return 0
+	}
+	t.Entries = append(t.Entries, Entry{})
+	copy(t.Entries[i+1:], t.Entries[i:])
+	t.Entries[i] = e
+	if len(t.Entries) > Size {
+		removeReplay(t.Entries[Size].Replay)
+		t.Entries = t.Entries[:Size]
+	}
+	return i + 1
+}
+
+// Place returns the place a score would take in the named table, counting
+// from 1, or 0 when it would not make the table.
+func (b *Book) Place(table string, lowerIsBetter bool, score int) int {
+	t := b.Tables[table]
+	if t == nil {
+		t = &Table{LowerIsBetter: lowerIsBetter}
+	}
+	if i := t.place(score); i < Size {
+		return i + 1
+	}
+	return 0
+}
+
+// place returns the index a score goes in at, after the scores as good.
+func (t *Table) place(score int) int {
+	better := func(a, b int) bool {
+		if t.LowerIsBetter {
+			return a < b
+		}
+		return a > b
+	}
+	return sort.Search(len(t.Entries), func(i int) bool { return better(score, t.Entries[i].Score) })
+}
+
+// Table returns the entries of the named table, best first.
+func (b *Book) Table(table string) []Entry {
+	if t := b.Tables[table]; t != nil {
+		return t.Entries
+	}
+	return nil
+}
+
+// SaveReplay keeps a replay next to the book and returns where, "" for a book
+// that is never saved.
+func (b *Book) SaveReplay(table string, r *sim.Replay) (string, error) {
+	if b.path == "" {
+		return "", nil
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%s-%d.json", table, time.Now().UnixNano())
+	path := filepath.Join(filepath.Dir(b.path), "replays", name)
+	return path, storage.WriteFile(path, data)
+}
+
+// removeReplay deletes a replay saved by SaveReplay. One that is gone
+// already is no loss.
+func removeReplay(path string) {
+	if path != "" {
+		storage.Remove(path)
+	}
+}
+
+// LoadReplay reads a replay saved by SaveReplay.
+func LoadReplay(path string) (*sim.Replay, error) {
+	data, err := storage.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &sim.Replay{}
+	return r, json.Unmarshal(data, r)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"log"
+	"math"
+
+	"ebiten/Snake/settings"
+
+	"github.com/hajimehoshi/ebiten"
+)
+
+// The game is drawn at one size, the HUD above the board, then scaled up to
+// whatever the window or the page is and letterboxed to keep its shape.
+const (
+	frameWidth  = screenWidth
+	frameHeight = screenHeight + hudHeight
+)
+
+// Layout makes the screen as many pixels as the window really has, so a
+// HiDPI display gets a sharp picture rather than one blown up afterwards.
+func (g *Game) Layout(outsideWidth, outsideHeight int) (int, int) {
+	s := ebiten.DeviceScaleFactor()
+	return int(float64(outsideWidth) * s), int(float64(outsideHeight) * s)
+}
+
+// setupScreen makes the window resizable and as it was left.
+func (g *Game) setupScreen() {
+	if g.prefs.WindowWidth <= 0 || g.prefs.WindowHeight <= 0 {
+		g.prefs.WindowWidth, g.prefs.WindowHeight = frameWidth, frameHeight
+	}
+	ebiten.SetWindowSize(g.prefs.WindowWidth, g.prefs.WindowHeight)
+	ebiten.SetWindowResizable(true)
+	ebiten.SetFullscreen(g.prefs.Fullscreen)
+}
+
+// updateScreen switches fullscreen and scaling, and keeps them and the size
+// of the window in the settings. The size is looked at once a second, not
+// to write the settings at every step of dragging a corner.
+func (g *Game) updateScreen() {
+	changed := false
+	if g.pressed(settings.ActionFullscreen) {
+		g.prefs.Fullscreen = !ebiten.IsFullscreen()
+		ebiten.SetFullscreen(g.prefs.Fullscreen)
+		changed = true
+	}
+	if g.pressed(settings.ActionScaling) {
+		if g.prefs.Scaling == settings.ScaleInteger {
+			g.prefs.Scaling = settings.ScaleFit
+		} else {
+			g.prefs.Scaling = settings.ScaleInteger
+		}
+		g.notify("Scaling: " + g.prefs.Scaling)
+		changed = true
+	}
+	if g.frames%ebiten.MaxTPS() == 0 && !ebiten.IsFullscreen() {
+		// Browsers have no window, it is 0 by 0 there.
+		if w, h := ebiten.WindowSize(); w > 0 && h > 0 && (w != g.prefs.WindowWidth || h != g.prefs.WindowHeight) {
+			g.prefs.WindowWidth, g.prefs.WindowHeight = w, h
+			changed = true
+		}
+	}
+	if changed {
+		if err := g.prefs.Save(); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// present scales the frame up to the screen and centres it. Integer scaling
+// keeps the pixels square and sharp, unless the screen is too small for
+// even one times, when it fits like the other.
+func (g *Game) present(screen *ebiten.Image) {
+	sw, sh := screen.Size()
+	scale := math.Min(float64(sw)/frameWidth, float64(sh)/frameHeight)
+	filter := ebiten.FilterLinear
+	if g.prefs.Scaling == settings.ScaleInteger && scale >= 1 {
+		scale, filter = math.Floor(scale), ebiten.FilterNearest
+	}
+	g.scale = scale
+	g.offsetX = math.Floor((float64(sw) - frameWidth*scale) / 2)
+	g.offsetY = math.Floor((float64(sh) - frameHeight*scale) / 2)
+
+	op := &ebiten.DrawImageOptions{Filter: filter}
+	op.GeoM.Scale(scale, scale)
+	op.GeoM.Translate(g.offsetX, g.offsetY)
+	screen.DrawImage(g.frame, op)
+}
+
+// toFrame turns a position on the screen into one on the frame.
+func (g *Game) toFrame(x, y int) (int, int) {
+	if g.scale == 0 {
+		return x, y
+	}
+	return int(math.Floor((float64(x) - g.offsetX) / g.scale)), int(math.Floor((float64(y) - g.offsetY) / g.scale))
+}
+
+// cursor returns where the mouse is on the frame.
+func (g *Game) cursor() (int, int) {
+	return g.toFrame(ebiten.CursorPosition())
+}
+
+// touchPosition returns where a touch is on the frame.
+func (g *Game) touchPosition(id int) (int, int) {
+	return g.toFrame(ebiten.TouchPosition(id))
+}
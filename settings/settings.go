@@ -0,0 +1,247 @@
+// Package settings keeps the player's preferences next to the scores, in the
+// player's home directory or in the browser.
+package settings
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"ebiten/Snake/storage"
+)
+
+// Actions the player can bind keys to.
+const (
+	ActionLeft    = "left"
+	ActionRight   = "right"
+	ActionDown    = "down"
+	ActionUp      = "up"
+	ActionReset   = "reset"
+	ActionAI      = "ai"
+	ActionPause   = "pause"
+	ActionStep    = "step"
+	ActionRewind  = "rewind"
+	ActionSlower  = "slower"
+	ActionFaster  = "faster"
+	ActionOverlay = "overlay"
+
+	ActionFullscreen = "fullscreen"
+	ActionScaling    = "scaling"
+)
+
+// Gamepad buttons that only mean something in menus, besides the actions.
+const (
+	ButtonConfirm = "confirm"
+	ButtonBack    = "back"
+)
+
+// Actions lists every action in the order they are shown.
+var Actions = []string{
+	ActionLeft, ActionRight, ActionDown, ActionUp, ActionReset, ActionAI,
+	ActionPause, ActionStep, ActionRewind, ActionSlower, ActionFaster, ActionOverlay,
+	ActionFullscreen, ActionScaling,
+}
+
+// Ways of scaling the screen up to the window.
+const (
+	ScaleFit     = "fit"     // as large as fits, smoothed
+	ScaleInteger = "integer" // a whole number of times, the pixels kept sharp
+)
+
+// DefaultKeys returns the keys every action starts out bound to, by the names
+// ebiten gives them.
+func DefaultKeys() map[string][]string {
+	return map[string][]string{
+		ActionLeft:    {"Left", "A"},
+		ActionRight:   {"Right", "D"},
+		ActionDown:    {"Down", "S"},
+		ActionUp:      {"Up", "W"},
+		ActionReset:   {"Escape"},
+		ActionAI:      {"Space"},
+		ActionPause:   {"P"},
+		ActionStep:    {"Period"},
+		ActionRewind:  {"Comma"},
+		ActionSlower:  {"Minus"},
+		ActionFaster:  {"Equal"},
+		ActionOverlay: {"F3"},
+
+		ActionFullscreen: {"F11"},
+		ActionScaling:    {"F10"},
+	}
+}
+
+// DefaultButtons returns the gamepad buttons every action starts out bound
+// to. The numbers are those of the standard layout browsers give gamepads:
+// 0 A, 1 B, 3 Y, 8 Back, 9 Start and 12 to 15 the D-pad.
+func DefaultButtons() map[string][]int {
+	return map[string][]int{
+		ActionLeft:    {14},
+		ActionRight:   {15},
+		ActionDown:    {13},
+		ActionUp:      {12},
+		ActionReset:   {8},
+		ActionAI:      {3},
+		ActionPause:   {9},
+		ButtonConfirm: {0},
+		ButtonBack:    {1},
+	}
+}
+
+// Settings are the player's preferences.
+type Settings struct {
+	// Keys binds each action to the names of any number of keys.
+	Keys map[string][]string `json:"keys"`
+
+	// Buttons binds actions, confirm and back to gamepad buttons.
+	Buttons map[string][]int `json:"buttons"`
+
+	// TouchDPad puts a D-pad on the screen once it is touched, for those
+	// who would rather not swipe.
+	TouchDPad bool `json:"touchDPad"`
+
+	// MouseSteering turns the snake towards the mouse pointer.
+	MouseSteering bool `json:"mouseSteering,omitempty"`
+
+	// Fullscreen and Scaling say how the game fills the screen, ScaleFit or
+	// ScaleInteger, and WindowWidth and WindowHeight how large the window
+	// was left, 0 if it never was resized.
+	Fullscreen   bool   `json:"fullscreen,omitempty"`
+	Scaling      string `json:"scaling"`
+	WindowWidth  int    `json:"windowWidth,omitempty"`
+	WindowHeight int    `json:"windowHeight,omitempty"`
+
+	// Theme is the name of the theme the game is drawn in.
+	Theme string `json:"theme,omitempty"`
+
+	// Outlines draws a line round everything on the board and FoodShapes
+	// gives every kind of food a shape of its own, so neither needs telling
+	// apart by colour. Speed slows a local game down to a percentage of how
+	// fast it goes, 100 if 0. AppleCues sounds out where the apple is, and
+	// Practice stops the player's snake short of anything it would crash
+	// into.
+	Outlines   bool `json:"outlines,omitempty"`
+	FoodShapes bool `json:"foodShapes,omitempty"`
+	Speed      int  `json:"speed,omitempty"`
+	AppleCues  bool `json:"appleCues,omitempty"`
+	Practice   bool `json:"practice,omitempty"`
+
+	// Particles fly off where apples are eaten and snakes crash, and
+	// ScreenShake shakes the board when the player's snake crashes.
+	Particles   bool `json:"particles"`
+	ScreenShake bool `json:"screenShake"`
+
+	path string
+}
+
+// Path returns where the settings are kept, "" when there is no home
+// directory to keep them in.
+func Path() string {
+	return storage.Path("settings.json")
+}
+
+// Load reads the settings at path. Whatever the file does not say, or all of
+// it if there is no file or no path, is left at the defaults.
+func Load(path string) (*Settings, error) {
+	s := &Settings{path: path, TouchDPad: true, Scaling: ScaleFit, Particles: true, ScreenShake: true}
+	defer s.fill()
+	if path == "" {
+		return s, nil
+	}
+	data, err := storage.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	return s, json.Unmarshal(data, s)
+}
+
+// fill binds the actions the settings know nothing of to their default keys
+// and buttons.
+// An action bound to no keys on purpose stays that way.
+func (s *Settings) fill() {
+	if s.Keys == nil {
+		s.Keys = map[string][]string{}
+	}
+	for action, keys := range DefaultKeys() {
+		if _, ok := s.Keys[action]; !ok {
+			s.Keys[action] = keys
+		}
+	}
+	if s.Buttons == nil {
+		s.Buttons = map[string][]int{}
+	}
+	for action, buttons := range DefaultButtons() {
+		if _, ok := s.Buttons[action]; !ok {
+			s.Buttons[action] = buttons
+		}
+	}
+}
+
+// Save writes the settings back to where they were loaded from.
+func (s *Settings) Save() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return storage.WriteFile(s.path, data)
+}
+
+// Action returns the action key is bound to, "" if none.
+func (s *Settings) Action(key string) string {
+	for _, action := range Actions {
+		for _, k := range s.Keys[action] {
+			if k == key {
+				return action
+			}
+		}
+	}
+	return ""
+}
+
+// Bind adds key to the keys of action. A key does one thing only, so it is
+// an error to bind it to a second action.
+func (s *Settings) Bind(action, key string) error {
+	if other := s.Action(key); other != "" {
+		return fmt.Errorf("%s is already bound to %s", key, other)
+	}
+	s.Keys[action] = append(s.Keys[action], key)
+	return nil
+}
+
+// Unbind takes the last key bound to action off it.
+func (s *Settings) Unbind(action string) {
+	if keys := s.Keys[action]; len(keys) > 0 {
+		s.Keys[action] = keys[:len(keys)-1]
+	}
+}
+
+// Reset binds every action to its default keys again.
+func (s *Settings) Reset() {
+	s.Keys = DefaultKeys()
+}
+
+// Conflicts describes every key bound to more than one action, which only a
+// settings file edited by hand can have.
+func (s *Settings) Conflicts() []string {
+	bound := map[string][]string{}
+	for _, action := range Actions {
+		for _, k := range s.Keys[action] {
+			bound[k] = append(bound[k], action)
+		}
+	}
+	var conflicts []string
+	for k, actions := range bound {
+		if len(actions) > 1 {
+			conflicts = append(conflicts, fmt.Sprintf("%s is bound to %s", k, strings.Join(actions, " and ")))
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts
+}
@@ -0,0 +1,144 @@
+package sim
+
+// AIMovement steers the snake towards the apple, or whatever else to eat is
+// worth going for.
+func (w *World) AIMovement(s *Snake) {
+	head := s.Body[0]
+	target := w.Waypoint(head, w.Target(s))
+	// Squared distance, which orders the same as the real one and stays
+	// in integers so every machine agrees on it.
+	dx, dy := head.X-target.X, head.Y-target.Y
+	length := dx*dx + dy*dy
+	if s.prevLength == 0 {
+		s.prevLength = length
+	}
+	// The snake can't turn back the way it came, whatever it turns to now.
+	was := s.Dir
+	if length < s.prevLength && length != 1 {
+		// Keep on moving in the same direction
+	} else {
+		// Find if we have to move up/down/left/right.
+		switch s.Dir {
+		case DirRight, DirLeft:
+			if target.Y > head.Y {
+				s.Dir = DirDown
+			} else {
+				s.Dir = DirUp
+			}
+		case DirDown, DirUp:
+			if target.X > head.X {
+				s.Dir = DirRight
+			} else {
+				s.Dir = DirLeft
+			}
+		}
+	}
+	s.prevLength = length
+
+	// Don't run into anything when there is another way to go.
+	if w.deadly(s, s.Dir) {
+		for _, d := range []int{DirUp, DirLeft, DirDown, DirRight} {
+			if d != opposite(was) && !w.deadly(s, d) {
+				s.Dir = d
+				break
+			}
+		}
+	}
+}
+
+// Target returns where the AI takes the snake: the food that gives the most
+// points for the way there, the apple if nothing beats it.
+func (w *World) Target(s *Snake) Position {
+	head := s.Body[0]
+	cost := func(p Position, points int) int {
+		d := w.Distance(head, p)
+		return d * d / points
+	}
+	best := w.Apple
+	bestCost := cost(w.Apple, foodKind(FoodApple).Points)
+	for _, f := range w.Food {
+		k := foodKind(f.Kind)
+		if k.Points <= 0 || k.Growth < 0 {
+			continue
+		}
+		if c := cost(f.Pos, k.Points); c < bestCost {
+			best, bestCost = f.Pos, c
+		}
+	}
+	return best
+}
+
+func opposite(dir int) int {
+	switch dir {
+	case DirLeft:
+		return DirRight
+	case DirRight:
+		return DirLeft
+	case DirDown:
+		return DirUp
+	case DirUp:
+		return DirDown
+	}
+	return DirNone
+}
+
+// deadly reports whether moving the snake's head one cell in dir kills it.
+func (w *World) deadly(s *Snake, dir int) bool {
+	if dir == DirNone {
+		return false
+	}
+	p := w.step(s.Body[0], dir)
+	if s.looked != nil {
+		*s.looked = append(*s.looked, p)
+	}
+	return !w.onBoard(p) || w.isWall(p) || w.occupied(p, nil)
+}
+
+// step returns where moving one cell from p in dir ends up: around the board
+// if it wraps and out of the partner if it is a portal.
+func (w *World) step(p Position, dir int) Position {
+	switch dir {
+	case DirLeft:
+		p.X--
+	case DirRight:
+		p.X++
+	case DirDown:
+		p.Y++
+	case DirUp:
+		p.Y--
+	}
+	if w.Wrap {
+		p.X = (p.X + w.Width) % w.Width
+		p.Y = (p.Y + w.Height) % w.Height
+	}
+	return w.Partner(p)
+}
+
+// PlannedPath returns the cells the AI is going to take the snake's head
+// through, at most max of them. It stops at the food it is after or where the
+// snake would die. The snake itself is not moved.
+func (w *World) PlannedPath(s *Snake, max int) []Position {
+	path, _ := w.PlanSearch(s, max)
+	return path
+}
+
+// PlanSearch is PlannedPath along with every cell the AI checked for danger
+// on the way, whether it went there or not.
+func (w *World) PlanSearch(s *Snake, max int) (path, visited []Position) {
+	if !s.AI || s.Dir == DirNone {
+		return nil, nil
+	}
+	c := *s
+	c.Body = append([]Position(nil), s.Body...)
+	c.looked = &visited
+	for i := 0; i < max; i++ {
+		target := w.Target(&c)
+		w.AIMovement(&c)
+		w.Advance(&c)
+		path = append(path, c.Head())
+		if c.Head() == target || w.collidesWithWall(&c) || w.collidesWithSelf(&c) {
+			break
+		}
+	}
+	return path, visited
+}
@@ -0,0 +1,124 @@
+package sim
+
+// Challenge modes. Each has a clock that starts with the first move.
+const (
+	// ModeTimeAttack is as many apples as possible before TimeLimit runs
+	// out. Crashing costs the length but not the score.
+	ModeTimeAttack = "timeattack"
+
+	// ModeSprint is reaching SprintLength as fast as possible. Crashing
+	// starts the snake over but not the clock.
+	ModeSprint = "sprint"
+
+	// ModeSurvival ends on the first crash or the first apple that is not
+	// eaten within AppleTimeout.
+	ModeSurvival = "survival"
+)
+
+// Challenge defaults, in frames at 60 a second.
+const (
+	defaultTimeLimit    = 60 * 60
+	defaultSprintLength = 30
+	defaultAppleTimeout = 10 * 60
+)
+
+// IsChallenge reports whether a mode is one of the challenges.
+func IsChallenge(mode string) bool {
+	return mode == ModeTimeAttack || mode == ModeSprint || mode == ModeSurvival
+}
+
+// ChallengeScore is what a challenge is ranked by: apples, or the frames it
+// took in a sprint. LowerIsBetter tells which way round.
+func (w *World) ChallengeScore(s *Snake) int {
+	if w.Mode == ModeSprint {
+		return w.Clock
+	}
+	return s.Score
+}
+
+// LowerIsBetter reports whether a smaller ChallengeScore ranks higher.
+func (w *World) LowerIsBetter() bool {
+	return w.Mode == ModeSprint
+}
+
+// TimeLeft returns the frames left before a time attack ends or a survival
+// apple goes bad, and -1 in the other modes.
+func (w *World) TimeLeft() int {
+	switch w.Mode {
+	case ModeTimeAttack:
+		return w.TimeLimit - w.Clock
+	case ModeSurvival:
+		return w.AppleExpires - w.Clock
+	}
+	return -1
+}
+
+// crash deals with a snake running into something and reports whether it
+// is out of the game.
+func (w *World) crash(s *Snake) bool {
+	s.Crashes++
+	switch w.Mode {
+	case ModeRoyale, ModeSurvival:
+		w.kill(s)
+		return true
+	case ModeTimeAttack, ModeSprint:
+		score := s.Score
+		w.reset(s)
+		s.Score = score
+		return false
+	}
+	w.reset(s)
+	return false
+}
+
+// tickChallenge runs the clock of a challenge once anyone is moving and ends
+// the game when its goal is met.
+func (w *World) tickChallenge() {
+	if w.Clock == 0 {
+		moving := false
+		for _, s := range w.Snakes {
+			moving = moving || s.Dir != DirNone
+		}
+		if !moving {
+			return
+		}
+		w.AppleExpires = w.appleTimeout
+	}
+	w.Clock++
+
+	switch w.Mode {
+	case ModeTimeAttack:
+		if w.Clock >= w.TimeLimit {
+			w.endChallenge(w.best())
+		}
+	case ModeSprint:
+		for _, s := range w.Snakes {
+			if len(s.Body) >= w.SprintLength {
+				w.endChallenge(s)
+				return
+			}
+		}
+	case ModeSurvival:
+		if w.Clock >= w.AppleExpires || w.Alive() == 0 {
+			w.endChallenge(w.best())
+		}
+	}
+}
+
+// best returns the snake with the highest score.
+func (w *World) best() *Snake {
+	var best *Snake
+	for _, s := range w.Snakes {
+		if best == nil || s.Score > best.Score {
+			best = s
+		}
+	}
+	return best
+}
+
+func (w *World) endChallenge(winner *Snake) {
+	w.Over = true
+	if winner != nil {
+		w.Winner = winner.ID
+	}
+}
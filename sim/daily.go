@@ -0,0 +1,28 @@
+package sim
+
+import "hash/fnv"
+
+// Daily returns the daily challenge of a date given as 2006-01-02: a one
+// minute time attack whose seed, board, walls, layout and speed all follow
+// from the date, so everybody playing it that day plays the same game.
+func Daily(date string) Config {
+	h := fnv.New64a()
+	h.Write([]byte(date))
+	seed := int64(h.Sum64())
+
+	r := newRNG(seed)
+	sizes := [][2]int{{64, 48}, {48, 36}, {32, 24}}
+	speeds := []int{3, 4, 6}
+	layouts := LayoutNames()
+	size := sizes[r.Intn(len(sizes))]
+	return Config{
+		Width:     size[0],
+		Height:    size[1],
+		Seed:      seed,
+		Wrap:      r.Intn(2) == 0,
+		MoveTime:  speeds[r.Intn(len(speeds))],
+		Mode:      ModeTimeAttack,
+		Layout:    layouts[r.Intn(len(layouts))],
+		TimeLimit: 60 * 60,
+	}
+}
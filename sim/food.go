@@ -0,0 +1,237 @@
+package sim
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Food kinds.
+const (
+	FoodApple   = "apple"
+	FoodGolden  = "golden"
+	FoodPoison  = "poison"
+	FoodRabbit  = "rabbit"
+	FoodRemains = "remains" // left behind by a dead snake
+)
+
+// FoodKind describes what one kind of food does to the snake that eats it.
+type FoodKind struct {
+	Kind string
+
+	// Points are added to the score and Growth segments to the snake. A
+	// negative Growth takes segments off, down to the head.
+	Points int
+	Growth int
+
+	// Chance is the odds, one in Chance, that a missing piece of the food
+	// turns up in a frame, right away if 0.
+	Chance int
+
+	// Lifetime is how many frames the food stays on the board, for good
+	// if 0.
+	Lifetime int
+
+	// MoveTime is how many frames the food takes per cell, 0 for food that
+	// stays put. Food that moves is prey: it runs from snakes it can see
+	// and wanders about at half the speed otherwise.
+	MoveTime int
+}
+
+// FoodKinds is the catalog of food. The apple is always on the board once,
+// the rest as often as Config.Food says.
+var FoodKinds = []FoodKind{
+	{Kind: FoodApple, Points: 1, Growth: 1},
+	{Kind: FoodGolden, Points: 5, Growth: 1, Chance: 600, Lifetime: 300},
+	{Kind: FoodPoison, Points: 0, Growth: -3, Chance: 300, Lifetime: 900},
+	{Kind: FoodRabbit, Points: 10, Growth: 2, Chance: 600, Lifetime: 1200, MoveTime: 6},
+	{Kind: FoodRemains, Points: 1, Growth: 1},
+}
+
+// DefaultFood is one of each kind of food besides the apple.
+var DefaultFood = map[string]int{
+	FoodGolden: 1,
+	FoodPoison: 1,
+	FoodRabbit: 1,
+}
+
+// Food is a piece of food on the board other than the apple.
+type Food struct {
+	Kind    string   `json:"kind"`
+	Pos     Position `json:"pos"`
+	Expires int      `json:"expires,omitempty"`
+
+	// Next is the frame food that moves takes its next step in.
+	Next int `json:"next,omitempty"`
+}
+
+func foodKind(kind string) FoodKind {
+	for _, k := range FoodKinds {
+		if k.Kind == kind {
+			return k
+		}
+	}
+	return FoodKinds[0]
+}
+
+// MaxFood is the most of one kind of food a board can have at once.
+const MaxFood = 10
+
+// CheckFood makes sure counts of food are ones a board can have.
+func CheckFood(food map[string]int) error {
+	for kind, n := range food {
+		if kind == FoodRemains || foodKind(kind).Kind != kind {
+			return fmt.Errorf("unknown food %q", kind)
+		}
+		if n < 0 || n > MaxFood {
+			return fmt.Errorf("a board holds 0 to %d of each food", MaxFood)
+		}
+	}
+	return nil
+}
+
+// ParseFood reads counts of food like "golden=1,poison=2". The count of
+// apples includes the apple itself.
+func ParseFood(s string) (map[string]int, error) {
+	food := map[string]int{}
+	if s == "" {
+		return food, nil
+	}
+	for _, f := range strings.Split(s, ",") {
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("food %q is not kind=count", f)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return nil, fmt.Errorf("bad count %q", kv[1])
+		}
+		food[strings.TrimSpace(kv[0])] = n
+	}
+	return food, CheckFood(food)
+}
+
+// FormatFood is the reverse of ParseFood.
+func FormatFood(food map[string]int) string {
+	var kinds []string
+	for k, n := range food {
+		if n > 0 {
+			kinds = append(kinds, fmt.Sprintf("%s=%d", k, n))
+		}
+	}
+	sort.Strings(kinds)
+	return strings.Join(kinds, ",")
+}
+
+// updateFood lets food run out, turn up and run away.
+func (w *World) updateFood() {
+	food := w.Food[:0]
+	for _, f := range w.Food {
+		if f.Expires == 0 || f.Expires > w.Timer {
+			food = append(food, f)
+		}
+	}
+	w.Food = food
+
+	// Walk the catalog rather than the map so every machine spawns the
+	// same food in the same order.
+	for _, k := range FoodKinds {
+		want := w.foodCounts[k.Kind]
+		if k.Kind == FoodApple {
+			want--
+		}
+		if want <= 0 || w.countFood(k.Kind) >= want {
+			continue
+		}
+		if k.Chance > 0 && w.rnd.Intn(k.Chance) != 0 {
+			continue
+		}
+		p := Position{X: w.rnd.Intn(w.Width), Y: w.rnd.Intn(w.Height)}
+		if !w.free(p) {
+			continue
+		}
+		f := Food{Kind: k.Kind, Pos: p}
+		if k.Lifetime > 0 {
+			f.Expires = w.Timer + k.Lifetime
+		}
+		if k.MoveTime > 0 {
+			f.Next = w.Timer + k.MoveTime
+		}
+		w.Food = append(w.Food, f)
+	}
+
+	for i := range w.Food {
+		if k := foodKind(w.Food[i].Kind); k.MoveTime > 0 && w.Food[i].Next <= w.Timer {
+			w.flee(&w.Food[i], k)
+		}
+	}
+}
+
+func (w *World) countFood(kind string) int {
+	n := 0
+	for _, f := range w.Food {
+		if f.Kind == kind {
+			n++
+		}
+	}
+	return n
+}
+
+// free reports whether nothing at all is on a cell.
+func (w *World) free(p Position) bool {
+	return w.onBoard(p) && !w.isWall(p) && !w.occupied(p, nil) &&
+		p != w.Apple && w.foodAt(p) < 0 && w.powerUpAt(p) < 0 && w.portalAt(p) < 0
+}
+
+func (w *World) foodAt(p Position) int {
+	for i, f := range w.Food {
+		if f.Pos == p {
+			return i
+		}
+	}
+	return -1
+}
+
+func (w *World) collidesWithFood(s *Snake) bool {
+	i := w.foodAt(s.Body[0])
+	if i < 0 {
+		return false
+	}
+	k := foodKind(w.Food[i].Kind)
+	w.Food = append(w.Food[:i], w.Food[i+1:]...)
+	w.eat(s, k)
+	return true
+}
+
+// eat grows the snake and scores as much as the food is worth.
+func (w *World) eat(s *Snake, k FoodKind) {
+	for i := 0; i < k.Growth; i++ {
+		s.Body = append(s.Body, s.Body[len(s.Body)-1])
+	}
+	if k.Growth < 0 {
+		n := len(s.Body) + k.Growth
+		if n < 1 {
+			n = 1
+		}
+		s.Body = s.Body[:n]
+	}
+	w.relevel(s)
+	s.Score += k.Points * w.points(s)
+	if s.Best < s.Score {
+		s.Best = s.Score
+	}
+}
+
+// relevel puts the snake on the level its length is worth and sets its
+// speed to match, slower again if it got shorter.
+func (w *World) relevel(s *Snake) {
+	if len(s.Body) > 10 && len(s.Body) < 20 {
+		s.Level = 2
+	} else if len(s.Body) > 20 {
+		s.Level = 3
+	} else {
+		s.Level = 1
+	}
+	s.MoveTime = w.levelMoveTime(s.Level)
+}
@@ -0,0 +1,49 @@
+package sim
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// Hash sums up everything that decides how the game goes on: the frame, the
+// food, the power-ups, the random source and every snake. Two worlds with
+// the same hash will play out the same given the same inputs.
+func (w *World) Hash() uint64 {
+	h := fnv.New64a()
+	var b [8]byte
+	put := func(v int64) {
+		binary.LittleEndian.PutUint64(b[:], uint64(v))
+		h.Write(b[:])
+	}
+	put(int64(w.Timer))
+	put(int64(w.Clock))
+	put(int64(w.AppleExpires))
+	put(int64(w.Apple.X))
+	put(int64(w.Apple.Y))
+	put(int64(w.rnd))
+	put(int64(len(w.Walls)))
+	for _, f := range w.Food {
+		h.Write([]byte(f.Kind))
+		put(int64(f.Pos.X))
+		put(int64(f.Pos.Y))
+		put(int64(f.Expires))
+		put(int64(f.Next))
+	}
+	for _, p := range w.PowerUps {
+		put(int64(p.Pos.X))
+		put(int64(p.Pos.Y))
+		put(int64(p.Expires))
+	}
+	for _, s := range w.Snakes {
+		put(int64(s.ID))
+		put(int64(s.Dir))
+		put(int64(s.Score))
+		put(int64(len(s.Effects)))
+		put(int64(len(s.Body)))
+		for _, v := range s.Body {
+			put(int64(v.X))
+			put(int64(v.Y))
+		}
+	}
+	return h.Sum64()
+}
@@ -0,0 +1,143 @@
+package sim
+
+import "sort"
+
+// Portal is a pair of cells. A head that moves onto one comes out of the
+// other, still going the same way, and the body follows it through.
+type Portal struct {
+	A Position `json:"a"`
+	B Position `json:"b"`
+}
+
+// Layout is what a board has on it before the game starts.
+type Layout struct {
+	Walls   []Position
+	Portals []Portal
+}
+
+// Layouts make the boards a Config can ask for by name. They are given the
+// size of the board so they fit any of them.
+var Layouts = map[string]func(width, height int) Layout{
+	LayoutOpen: func(width, height int) Layout {
+		return Layout{}
+	},
+
+	// Two pairs of portals joining opposite sides of the board.
+	LayoutPortals: func(width, height int) Layout {
+		return Layout{Portals: []Portal{
+			{A: Position{X: 2, Y: height / 2}, B: Position{X: width - 3, Y: height / 2}},
+			{A: Position{X: width / 2, Y: 2}, B: Position{X: width / 2, Y: height - 3}},
+		}}
+	},
+
+	// A walled box in the middle with a way in on each side and a portal in
+	// and out of each of two corners.
+	LayoutBox: func(width, height int) Layout {
+		var l Layout
+		x0, y0, x1, y1 := width/4, height/4, width*3/4, height*3/4
+		for x := x0; x <= x1; x++ {
+			if x != width/2 {
+				l.Walls = append(l.Walls, Position{X: x, Y: y0}, Position{X: x, Y: y1})
+			}
+		}
+		for y := y0 + 1; y < y1; y++ {
+			if y != height/2 {
+				l.Walls = append(l.Walls, Position{X: x0, Y: y}, Position{X: x1, Y: y})
+			}
+		}
+		l.Portals = []Portal{
+			{A: Position{X: 2, Y: 2}, B: Position{X: x0 + 2, Y: y0 + 2}},
+			{A: Position{X: width - 3, Y: height - 3}, B: Position{X: x1 - 2, Y: y1 - 2}},
+		}
+		return l
+	},
+}
+
+// Layout names.
+const (
+	LayoutOpen    = "open"
+	LayoutPortals = "portals"
+	LayoutBox     = "box"
+)
+
+// LayoutNames returns the names of all layouts in order.
+func LayoutNames() []string {
+	var names []string
+	for name := range Layouts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (w *World) portalAt(p Position) int {
+	for i, v := range w.Portals {
+		if v.A == p || v.B == p {
+			return i
+		}
+	}
+	return -1
+}
+
+// Partner returns the other end of the portal at p, p itself if there is no
+// portal there.
+func (w *World) Partner(p Position) Position {
+	for _, v := range w.Portals {
+		switch p {
+		case v.A:
+			return v.B
+		case v.B:
+			return v.A
+		}
+	}
+	return p
+}
+
+// Distance returns how many cells apart two cells are, ignoring everything
+// in the way but going through a portal where that is shorter.
+func (w *World) Distance(a, b Position) int {
+	d := manhattan(a, b)
+	for _, v := range w.Portals {
+		if a == v.A || a == v.B {
+			// Moving off a portal doesn't go through it.
+			continue
+		}
+		if via := manhattan(a, v.A) + manhattan(v.B, b); via < d {
+			d = via
+		}
+		if via := manhattan(a, v.B) + manhattan(v.A, b); via < d {
+			d = via
+		}
+	}
+	return d
+}
+
+// Waypoint returns where to head for to get from a to b the shortest way:
+// b itself or the portal that leads closest to it.
+func (w *World) Waypoint(a, b Position) Position {
+	best, d := b, manhattan(a, b)
+	for _, v := range w.Portals {
+		if a == v.A || a == v.B {
+			// Moving off a portal doesn't go through it.
+			continue
+		}
+		if via := manhattan(a, v.A) + manhattan(v.B, b); via < d {
+			best, d = v.A, via
+		}
+		if via := manhattan(a, v.B) + manhattan(v.A, b); via < d {
+			best, d = v.B, via
+		}
+	}
+	return best
+}
+
+func manhattan(a, b Position) int {
+	dx, dy := a.X-b.X, a.Y-b.Y
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	return dx + dy
+}
@@ -0,0 +1,163 @@
+package sim
+
+// Power-up kinds.
+const (
+	PowerSpeed  = "speed"  // moves twice as fast
+	PowerSlow   = "slow"   // moves half as fast
+	PowerGhost  = "ghost"  // goes through its own body
+	PowerDouble = "double" // scores twice
+	PowerShrink = "shrink" // loses half its tail at once
+)
+
+// PowerUpKind describes one kind of power-up.
+type PowerUpKind struct {
+	Kind string
+
+	// Chance is the odds, one in Chance, that the power-up turns up on
+	// the board in a frame.
+	Chance int
+
+	// Lifetime is how many frames the power-up stays on the board.
+	Lifetime int
+
+	// Duration is how many frames the effect lasts once eaten, 0 for an
+	// effect that happens at once.
+	Duration int
+}
+
+// PowerUpKinds are the power-ups that can turn up when a board has them.
+var PowerUpKinds = []PowerUpKind{
+	{Kind: PowerSpeed, Chance: 900, Lifetime: 600, Duration: 300},
+	{Kind: PowerSlow, Chance: 900, Lifetime: 600, Duration: 300},
+	{Kind: PowerGhost, Chance: 1200, Lifetime: 480, Duration: 480},
+	{Kind: PowerDouble, Chance: 1200, Lifetime: 480, Duration: 600},
+	{Kind: PowerShrink, Chance: 1500, Lifetime: 480},
+}
+
+// maxPowerUps is how many power-ups can be on the board at once.
+const maxPowerUps = 3
+
+// PowerUp is a power-up lying on the board.
+type PowerUp struct {
+	Kind    string   `json:"kind"`
+	Pos     Position `json:"pos"`
+	Expires int      `json:"expires"`
+}
+
+// Effect is a power-up working on a snake until the frame Until.
+type Effect struct {
+	Kind  string `json:"kind"`
+	Until int    `json:"until"`
+}
+
+// HasEffect reports whether a power-up of the given kind works on the snake.
+func (s *Snake) HasEffect(kind string) bool {
+	for _, e := range s.Effects {
+		if e.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// MoveTime returns how many frames the snake takes per cell right now.
+func (w *World) MoveTime(s *Snake) int {
+	t := s.MoveTime
+	if s.HasEffect(PowerSpeed) {
+		t = (t + 1) / 2
+	}
+	if s.HasEffect(PowerSlow) {
+		t *= 2
+	}
+	return t
+}
+
+// points is what eating something is worth to the snake.
+func (w *World) points(s *Snake) int {
+	if s.HasEffect(PowerDouble) {
+		return 2
+	}
+	return 1
+}
+
+// updatePowerUps lets power-ups turn up and run out, and effects wear off.
+func (w *World) updatePowerUps() {
+	ups := w.PowerUps[:0]
+	for _, p := range w.PowerUps {
+		if p.Expires > w.Timer {
+			ups = append(ups, p)
+		}
+	}
+	w.PowerUps = ups
+
+	for _, s := range w.Snakes {
+		effects := s.Effects[:0]
+		for _, e := range s.Effects {
+			if e.Until > w.Timer {
+				effects = append(effects, e)
+			}
+		}
+		s.Effects = effects
+	}
+
+	for _, k := range PowerUpKinds {
+		if len(w.PowerUps) >= maxPowerUps {
+			return
+		}
+		if w.rnd.Intn(k.Chance) != 0 {
+			continue
+		}
+		p := Position{X: w.rnd.Intn(w.Width), Y: w.rnd.Intn(w.Height)}
+		if !w.free(p) {
+			continue
+		}
+		w.PowerUps = append(w.PowerUps, PowerUp{Kind: k.Kind, Pos: p, Expires: w.Timer + k.Lifetime})
+	}
+}
+
+func (w *World) powerUpAt(p Position) int {
+	for i, v := range w.PowerUps {
+		if v.Pos == p {
+			return i
+		}
+	}
+	return -1
+}
+
+func (w *World) collidesWithPowerUp(s *Snake) bool {
+	i := w.powerUpAt(s.Body[0])
+	if i < 0 {
+		return false
+	}
+	kind := w.PowerUps[i].Kind
+	w.PowerUps = append(w.PowerUps[:i], w.PowerUps[i+1:]...)
+	w.applyPowerUp(s, kind)
+	return true
+}
+
+func (w *World) applyPowerUp(s *Snake, kind string) {
+	for _, k := range PowerUpKinds {
+		if k.Kind != kind {
+			continue
+		}
+		if k.Duration == 0 {
+			break
+		}
+		// Speed and slow-mo cancel each other out rather than stack.
+		for i := 0; i < len(s.Effects); i++ {
+			e := s.Effects[i]
+			if e.Kind == kind ||
+				(kind == PowerSpeed && e.Kind == PowerSlow) ||
+				(kind == PowerSlow && e.Kind == PowerSpeed) {
+				s.Effects = append(s.Effects[:i], s.Effects[i+1:]...)
+				i--
+			}
+		}
+		s.Effects = append(s.Effects, Effect{Kind: kind, Until: w.Timer + k.Duration})
+		return
+	}
+	if kind == PowerShrink && len(s.Body) > 1 {
+		s.Body = s.Body[:(len(s.Body)+1)/2]
+		w.relevel(s)
+	}
+}
@@ -0,0 +1,87 @@
+package sim
+
+// preySight is how many steps away prey notices a snake.
+const preySight = 12
+
+// flee moves prey one step. It goes where the snakes need the most steps to
+// get to, so it keeps away from heads that have to go around walls and bodies
+// rather than from the ones that merely look close.
+func (w *World) flee(f *Food, k FoodKind) {
+	if w.occupied(f.Pos, nil) {
+		// Caught, the snake eats it on its next move.
+		return
+	}
+	dist := w.headDistances()
+	at := func(p Position) int {
+		if d := dist[p.Y*w.Width+p.X]; d >= 0 {
+			return d
+		}
+		return w.Width * w.Height
+	}
+
+	var moves []Position
+	for _, d := range []int{DirUp, DirLeft, DirDown, DirRight} {
+		if p := w.step(f.Pos, d); w.free(p) {
+			moves = append(moves, p)
+		}
+	}
+	f.Next = w.Timer + k.MoveTime
+	if at(f.Pos) > preySight {
+		// Nothing to run from, hop about at leisure.
+		f.Next += k.MoveTime
+		if len(moves) > 0 {
+			f.Pos = moves[w.rnd.Intn(len(moves))]
+		}
+		return
+	}
+	best := f.Pos
+	for _, p := range moves {
+		if at(p) > at(best) {
+			best = p
+		}
+	}
+	f.Pos = best
+}
+
+// headDistances returns how many steps the nearest snake head is from each
+// cell, found by a breadth-first search from all heads at once. Walls and
+// bodies block the way, cells no snake can get to are -1.
+func (w *World) headDistances() []int {
+	dist := make([]int, w.Width*w.Height)
+	for i := range dist {
+		dist[i] = -1
+	}
+	// Bodies are marked -2 until the search is done so it never enters them.
+	var queue []Position
+	for _, s := range w.Snakes {
+		for i, v := range s.Body {
+			if !w.onBoard(v) {
+				continue
+			}
+			if i == 0 {
+				dist[v.Y*w.Width+v.X] = 0
+				queue = append(queue, v)
+			} else if dist[v.Y*w.Width+v.X] != 0 {
+				dist[v.Y*w.Width+v.X] = -2
+			}
+		}
+	}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, d := range []int{DirUp, DirLeft, DirDown, DirRight} {
+			n := w.step(p, d)
+			if !w.onBoard(n) || w.isWall(n) || dist[n.Y*w.Width+n.X] != -1 {
+				continue
+			}
+			dist[n.Y*w.Width+n.X] = dist[p.Y*w.Width+p.X] + 1
+			queue = append(queue, n)
+		}
+	}
+	for i, d := range dist {
+		if d == -2 {
+			dist[i] = -1
+		}
+	}
+	return dist
+}
@@ -0,0 +1,23 @@
+package sim
+
+// rng is a splitmix64 generator. Its whole state is one number, so a cloned
+// world goes on drawing the same numbers as the original would, which
+// rollback and lockstep play depend on.
+type rng uint64
+
+func newRNG(seed int64) rng {
+	return rng(seed)
+}
+
+func (r *rng) next() uint64 {
+	*r += 0x9e3779b97f4a7c15
+	z := uint64(*r)
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Intn returns a number in [0, n).
+func (r *rng) Intn(n int) int {
+	return int(r.next() % uint64(n))
+}
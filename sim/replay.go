@@ -0,0 +1,58 @@
+package sim
+
+// Replay is a single player game as the board it was played on and what the
+// player did. The rules are deterministic, so that is all it takes to play
+// the game again exactly as it went.
+type Replay struct {
+	Date   string `json:"date,omitempty"` // of a daily challenge
+	Name   string `json:"name"`
+	Config Config `json:"config"`
+	Steps  []Step `json:"steps"`
+}
+
+// Step is the input of the frame Frame.
+type Step struct {
+	Frame int   `json:"frame"`
+	Input Input `json:"input"`
+}
+
+// ReplayID is the id of the snake in a replay.
+const ReplayID = 1
+
+// Record adds the player's input in the world's current frame.
+func (r *Replay) Record(w *World, in Input) {
+	if in != (Input{}) {
+		r.Steps = append(r.Steps, Step{Frame: w.Timer, Input: in})
+	}
+}
+
+// NewWorld returns the board the replay starts on.
+func (r *Replay) NewWorld() *World {
+	w := NewWorld(r.Config)
+	w.AddSnake(ReplayID).Name = r.Name
+	return w
+}
+
+// Apply gives the world the input recorded for its current frame. It
+// returns the index of the next step to apply.
+func (r *Replay) Apply(w *World, next int) int {
+	for next < len(r.Steps) && r.Steps[next].Frame <= w.Timer {
+		if r.Steps[next].Frame == w.Timer {
+			w.Apply(ReplayID, r.Steps[next].Input)
+		}
+		next++
+	}
+	return next
+}
+
+// Play runs the replay to its end, at most max frames, and returns the
+// world as the game left it.
+func (r *Replay) Play(max int) *World {
+	w := r.NewWorld()
+	next := 0
+	for !w.Over && w.Timer < max {
+		next = r.Apply(w, next)
+		w.Update()
+	}
+	return w
+}
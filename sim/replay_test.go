@@ -0,0 +1,88 @@
+package sim
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// chase is a player that heads straight for the apple, turning on every
+// frame it has to, until it hands the snake to the AI.
+func chase(w *World) Input {
+	s := w.Snake(ReplayID)
+	if s == nil || len(s.Body) == 0 || s.AI {
+		return Input{}
+	}
+	head, dir := s.Head(), DirNone
+	switch {
+	case head.X < w.Apple.X:
+		dir = DirRight
+	case head.X > w.Apple.X:
+		dir = DirLeft
+	case head.Y < w.Apple.Y:
+		dir = DirDown
+	case head.Y > w.Apple.Y:
+		dir = DirUp
+	}
+	if dir == s.Dir {
+		return Input{}
+	}
+	return Input{Dir: dir}
+}
+
+func TestReplayPlay(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  Config
+		ai   int // frame the AI takes over, never if 0
+	}{
+		{"classic", Config{Width: 32, Height: 24, Seed: 1}, 0},
+		{"wrap", Config{Width: 32, Height: 24, Seed: 2, Wrap: true}, 0},
+		{"food", Config{Width: 48, Height: 36, Seed: 3, PowerUps: true, Food: DefaultFood}, 0},
+		{"ai", Config{Width: 32, Height: 24, Seed: 4}, 500},
+		{"timeattack", Config{Width: 32, Height: 24, Seed: 5, Mode: ModeTimeAttack, TimeLimit: 1200}, 0},
+		{"survival", Config{Width: 32, Height: 24, Seed: 6, Mode: ModeSurvival}, 0},
+		{"daily", Daily("2026-10-18"), 1},
+	}
+	const frames = 3000
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rp := &Replay{Name: "test", Config: tc.cfg}
+			live := rp.NewWorld()
+			for !live.Over && live.Timer < frames {
+				in := chase(live)
+				if tc.ai != 0 && live.Timer == tc.ai {
+					in.ToggleAI = true
+				}
+				rp.Record(live, in)
+				live.Apply(ReplayID, in)
+				live.Update()
+			}
+			if live.Snake(ReplayID).Best == 0 {
+				t.Fatal("the live run ate nothing, there is nothing to check")
+			}
+
+			// Replays are kept and posted as JSON.
+			data, err := json.Marshal(rp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var loaded Replay
+			if err := json.Unmarshal(data, &loaded); err != nil {
+				t.Fatal(err)
+			}
+
+			played := loaded.Play(frames)
+			s, want := played.Snake(ReplayID), live.Snake(ReplayID)
+			if played.Timer != live.Timer || played.Over != live.Over {
+				t.Errorf("played to frame %d, over %v; the live run to %d, over %v",
+					played.Timer, played.Over, live.Timer, live.Over)
+			}
+			if s.Score != want.Score || s.Best != want.Best || played.ChallengeScore(s) != live.ChallengeScore(want) {
+				t.Errorf("played score %d, best %d; the live run %d, %d", s.Score, s.Best, want.Score, want.Best)
+			}
+			if played.Hash() != live.Hash() {
+				t.Error("the replay ends on another board than the live run")
+			}
+		})
+	}
+}
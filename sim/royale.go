@@ -0,0 +1,110 @@
+package sim
+
+// minArena is the smallest a shrinking board gets.
+const minArena = 6
+
+func (w *World) isWall(p Position) bool {
+	if len(w.Walls) == 0 {
+		return false
+	}
+	if w.wallSet == nil {
+		w.wallSet = make(map[Position]bool, len(w.Walls))
+		for _, v := range w.Walls {
+			w.wallSet[v] = true
+		}
+	}
+	return w.wallSet[p]
+}
+
+// AddWall turns a cell into a wall.
+func (w *World) AddWall(p Position) {
+	if w.isWall(p) {
+		return
+	}
+	w.Walls = append(w.Walls, p)
+	if w.wallSet != nil {
+		w.wallSet[p] = true
+	}
+}
+
+func (w *World) onBoard(p Position) bool {
+	return p.X >= 0 && p.Y >= 0 && p.X < w.Width && p.Y < w.Height
+}
+
+// kill takes a snake out of the game for good. What is left of its body on
+// the board becomes food for the others.
+func (w *World) kill(s *Snake) {
+	for _, v := range s.Body {
+		if w.onBoard(v) && !w.isWall(v) && v != w.Apple && w.foodAt(v) < 0 {
+			w.Food = append(w.Food, Food{Kind: FoodRemains, Pos: v})
+		}
+	}
+	s.Body = s.Body[:0]
+	s.Dead = true
+	s.Dir = DirNone
+	s.Effects = nil
+}
+
+// Alive returns the number of snakes still in the game.
+func (w *World) Alive() int {
+	n := 0
+	for _, s := range w.Snakes {
+		if !s.Dead {
+			n++
+		}
+	}
+	return n
+}
+
+// shrink closes the walls in by one cell every shrinkEvery frames.
+func (w *World) shrink() {
+	if w.shrinkEvery <= 0 || w.Timer == 0 || w.Timer%w.shrinkEvery != 0 {
+		return
+	}
+	r := w.ring
+	if w.Width-2*(r+1) < minArena || w.Height-2*(r+1) < minArena {
+		return
+	}
+	for x := r; x < w.Width-r; x++ {
+		w.AddWall(Position{X: x, Y: r})
+		w.AddWall(Position{X: x, Y: w.Height - 1 - r})
+	}
+	for y := r + 1; y < w.Height-1-r; y++ {
+		w.AddWall(Position{X: r, Y: y})
+		w.AddWall(Position{X: w.Width - 1 - r, Y: y})
+	}
+	w.ring++
+
+	food := w.Food[:0]
+	for _, f := range w.Food {
+		if !w.isWall(f.Pos) {
+			food = append(food, f)
+		}
+	}
+	w.Food = food
+	ups := w.PowerUps[:0]
+	for _, p := range w.PowerUps {
+		if !w.isWall(p.Pos) {
+			ups = append(ups, p)
+		}
+	}
+	w.PowerUps = ups
+	if w.isWall(w.Apple) {
+		w.placeApple()
+	}
+}
+
+// checkWinner ends the game once at most one snake is left, or none when
+// there was only one to begin with.
+func (w *World) checkWinner() {
+	alive := w.Alive()
+	if alive > 1 || (alive == 1 && len(w.Snakes) == 1) {
+		return
+	}
+	w.Over = true
+	for _, s := range w.Snakes {
+		if !s.Dead {
+			w.Winner = s.ID
+		}
+	}
+}
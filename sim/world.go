@@ -0,0 +1,535 @@
+// Package sim holds the rules of the game. It has no dependency on ebiten so
+// the same rules can run in the window, on the server and in headless tools.
+package sim
+
+const (
+	DirNone = iota
+	DirLeft
+	DirRight
+	DirDown
+	DirUp
+)
+
+// Position is a cell on the board.
+type Position struct {
+	X int
+	Y int
+}
+
+// Config describes a board.
+type Config struct {
+	Width  int   `json:"width"`
+	Height int   `json:"height"`
+	Seed   int64 `json:"seed"`
+
+	// Wrap makes snakes leave one edge and come back at the other instead
+	// of dying on it.
+	Wrap bool `json:"wrap,omitempty"`
+
+	// MoveTime is how many frames a snake takes per cell at level 1, 4 if
+	// unset. Every level after that takes one frame less.
+	MoveTime int `json:"moveTime,omitempty"`
+
+	// Mode is ModeClassic if unset.
+	Mode string `json:"mode,omitempty"`
+
+	// ShrinkEvery is how many frames pass between the walls of a
+	// ModeRoyale board closing in by one cell, 300 if unset.
+	ShrinkEvery int `json:"shrinkEvery,omitempty"`
+
+	// PowerUps lets the power-ups in PowerUpKinds turn up on the board.
+	PowerUps bool `json:"powerUps,omitempty"`
+
+	// Food is how many of each kind in FoodKinds the board has at once
+	// besides the one apple.
+	Food map[string]int `json:"food,omitempty"`
+
+	// Layout names one of Layouts, LayoutOpen if unset.
+	Layout string `json:"layout,omitempty"`
+
+	// Practice stops snakes the AI isn't playing short of what they would
+	// crash into, rather than letting them crash.
+	Practice bool `json:"practice,omitempty"`
+
+	// TimeLimit is how many frames a ModeTimeAttack game lasts, a minute
+	// if unset. SprintLength is how long a snake must get in ModeSprint,
+	// 30 if unset. AppleTimeout is how many frames an apple lasts in
+	// ModeSurvival, ten seconds if unset.
+	TimeLimit    int `json:"timeLimit,omitempty"`
+	SprintLength int `json:"sprintLength,omitempty"`
+	AppleTimeout int `json:"appleTimeout,omitempty"`
+}
+
+const defaultShrinkEvery = 300
+
+const defaultMoveTime = 4
+
+// Game modes.
+const (
+	// ModeClassic respawns a snake that dies and goes on forever.
+	ModeClassic = "classic"
+
+	// ModeRoyale never respawns. The board shrinks every ShrinkEvery
+	// frames, dead snakes leave their body behind as food and the last
+	// snake alive wins.
+	ModeRoyale = "royale"
+)
+
+// Snake is one player on the board.
+type Snake struct {
+	ID       int        `json:"id"`
+	Name     string     `json:"name,omitempty"`
+	Body     []Position `json:"body"`
+	Dir      int        `json:"dir"`
+	Score    int        `json:"score"`
+	Best     int        `json:"best"`
+	Level    int        `json:"level"`
+	MoveTime int        `json:"moveTime"`
+	AI       bool       `json:"ai"`
+	Dead     bool       `json:"dead,omitempty"`
+	Effects  []Effect   `json:"effects,omitempty"`
+	Crashes  int        `json:"crashes,omitempty"`
+
+	prevLength int
+	looked     *[]Position // where deadly records the cells it checks, if set
+}
+
+// Head returns the first segment of the snake.
+func (s *Snake) Head() Position {
+	return s.Body[0]
+}
+
+// World is the whole board. Update advances it by one frame.
+type World struct {
+	Width  int      `json:"width"`
+	Height int      `json:"height"`
+	Timer  int      `json:"timer"`
+	Apple  Position `json:"apple"`
+	Snakes []*Snake `json:"snakes"`
+	Wrap   bool     `json:"wrap,omitempty"`
+	Mode   string   `json:"mode"`
+
+	// Walls are cells that kill like the edge of the board.
+	Walls []Position `json:"walls,omitempty"`
+
+	Portals []Portal `json:"portals,omitempty"`
+
+	// Food is everything to eat besides the apple.
+	Food []Food `json:"food,omitempty"`
+
+	// PowerUps are lying on the board waiting to be eaten. They only turn
+	// up when Config.PowerUps is set.
+	PowerUps []PowerUp `json:"powerUps,omitempty"`
+
+	// Practice is set from Config.Practice, and can be changed as the game
+	// goes on.
+	Practice bool `json:"practice,omitempty"`
+
+	// Over is set when a game that can end has ended, Winner is the id
+	// of the snake that won if any did.
+	Over   bool `json:"over,omitempty"`
+	Winner int  `json:"winner,omitempty"`
+
+	// Clock counts the frames of a challenge since the first move. The
+	// challenge ends at TimeLimit, SprintLength or when Clock reaches
+	// AppleExpires depending on the mode.
+	Clock        int `json:"clock,omitempty"`
+	TimeLimit    int `json:"timeLimit,omitempty"`
+	SprintLength int `json:"sprintLength,omitempty"`
+	AppleExpires int `json:"appleExpires,omitempty"`
+
+	moveTime     int
+	shrinkEvery  int
+	powerUps     bool
+	foodCounts   map[string]int
+	appleTimeout int
+	ring         int
+	wallSet      map[Position]bool
+	rnd          rng
+}
+
+func NewWorld(cfg Config) *World {
+	w := &World{
+		Width:        cfg.Width,
+		Height:       cfg.Height,
+		Wrap:         cfg.Wrap,
+		Practice:     cfg.Practice,
+		Mode:         cfg.Mode,
+		moveTime:     cfg.MoveTime,
+		shrinkEvery:  cfg.ShrinkEvery,
+		powerUps:     cfg.PowerUps,
+		foodCounts:   map[string]int{},
+		TimeLimit:    cfg.TimeLimit,
+		SprintLength: cfg.SprintLength,
+		appleTimeout: cfg.AppleTimeout,
+		rnd:          newRNG(cfg.Seed),
+	}
+	if w.moveTime <= 0 {
+		w.moveTime = defaultMoveTime
+	}
+	if w.Mode == "" {
+		w.Mode = ModeClassic
+	}
+	if w.shrinkEvery <= 0 {
+		w.shrinkEvery = defaultShrinkEvery
+	}
+	if w.TimeLimit <= 0 {
+		w.TimeLimit = defaultTimeLimit
+	}
+	if w.SprintLength <= 0 {
+		w.SprintLength = defaultSprintLength
+	}
+	if w.appleTimeout <= 0 {
+		w.appleTimeout = defaultAppleTimeout
+	}
+	for k, n := range cfg.Food {
+		w.foodCounts[k] = n
+	}
+	if layout, ok := Layouts[cfg.Layout]; ok {
+		l := layout(w.Width, w.Height)
+		for _, p := range l.Walls {
+			w.AddWall(p)
+		}
+		w.Portals = append(w.Portals, l.Portals...)
+	}
+	w.Apple = w.startApple()
+	return w
+}
+
+// startApple is where the apple waits for a new game, pulled onto the board
+// if it is too small and put somewhere else if a wall or portal is there.
+func (w *World) startApple() Position {
+	p := Position{X: 30, Y: 30}
+	if p.X >= w.Width {
+		p.X = w.Width / 4
+	}
+	if p.Y >= w.Height {
+		p.Y = w.Height / 4
+	}
+	if !w.spawnable(p, nil) {
+		w.placeApple()
+		return w.Apple
+	}
+	return p
+}
+
+// spawnPoint picks a free cell for a snake, the middle of the board first
+// where the single player game has always started.
+func (w *World) spawnPoint(s *Snake) Position {
+	candidates := []Position{
+		{X: w.Width / 2, Y: w.Height / 2},
+		{X: w.Width / 4, Y: w.Height / 4},
+		{X: w.Width * 3 / 4, Y: w.Height * 3 / 4},
+		{X: w.Width * 3 / 4, Y: w.Height / 4},
+		{X: w.Width / 4, Y: w.Height * 3 / 4},
+	}
+	for _, p := range candidates {
+		if w.spawnable(p, s) {
+			return p
+		}
+	}
+	for {
+		p := Position{X: w.rnd.Intn(w.Width), Y: w.rnd.Intn(w.Height)}
+		if w.spawnable(p, s) {
+			return p
+		}
+	}
+}
+
+func (w *World) spawnable(p Position, s *Snake) bool {
+	return !w.occupied(p, s) && !w.isWall(p) && w.portalAt(p) < 0
+}
+
+func (w *World) occupied(p Position, except *Snake) bool {
+	for _, o := range w.Snakes {
+		if o == except {
+			continue
+		}
+		for _, v := range o.Body {
+			if v == p {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// AddSnake puts a new snake with the given id on the board.
+func (w *World) AddSnake(id int) *Snake {
+	s := &Snake{ID: id, Body: make([]Position, 1)}
+	w.Snakes = append(w.Snakes, s)
+	w.reset(s)
+	return s
+}
+
+// Leave takes the snake with the given id out of the game as if it had died,
+// so a royale still ends when one snake is left.
+func (w *World) Leave(id int) {
+	if s := w.Snake(id); s != nil && !s.Dead {
+		w.kill(s)
+	}
+}
+
+// Snake returns the snake with the given id or nil.
+func (w *World) Snake(id int) *Snake {
+	for _, s := range w.Snakes {
+		if s.ID == id {
+			return s
+		}
+	}
+	return nil
+}
+
+// SetDirection turns a snake unless that would reverse it onto itself.
+func (w *World) SetDirection(id, dir int) {
+	s := w.Snake(id)
+	if s == nil || s.Dead {
+		return
+	}
+	switch dir {
+	case DirLeft:
+		if s.Dir != DirRight {
+			s.Dir = dir
+		}
+	case DirRight:
+		if s.Dir != DirLeft {
+			s.Dir = dir
+		}
+	case DirDown:
+		if s.Dir != DirUp {
+			s.Dir = dir
+		}
+	case DirUp:
+		if s.Dir != DirDown {
+			s.Dir = dir
+		}
+	}
+}
+
+// Reset puts a snake back at its spawn point. Snakes only get a second
+// chance in the classic game.
+func (w *World) Reset(id int) {
+	if s := w.Snake(id); s != nil && w.Mode == ModeClassic {
+		w.reset(s)
+	}
+}
+
+func (w *World) reset(s *Snake) {
+	// With a single snake the whole board starts over like it always did.
+	if len(w.Snakes) == 1 {
+		w.Apple = w.startApple()
+	}
+	s.MoveTime = w.moveTime
+	s.Body = s.Body[:1]
+	s.Body[0] = w.spawnPoint(s)
+	s.Score = 0
+	s.Level = 1
+	s.Dir = DirNone
+	s.Effects = nil
+	s.prevLength = 0
+}
+
+func (w *World) collidesWithApple(s *Snake) bool {
+	return s.Body[0].X == w.Apple.X &&
+		s.Body[0].Y == w.Apple.Y
+}
+
+func (w *World) collidesWithSelf(s *Snake) bool {
+	if s.HasEffect(PowerGhost) {
+		return false
+	}
+	for _, v := range s.Body[1:] {
+		if s.Body[0].X == v.X &&
+			s.Body[0].Y == v.Y {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *World) collidesWithOthers(s *Snake) bool {
+	return w.occupied(s.Body[0], s)
+}
+
+func (w *World) collidesWithWall(s *Snake) bool {
+	if w.isWall(s.Body[0]) {
+		return true
+	}
+	if w.Wrap {
+		return false
+	}
+	return s.Body[0].X < 0 ||
+		s.Body[0].Y < 0 ||
+		s.Body[0].X >= w.Width ||
+		s.Body[0].Y >= w.Height
+}
+
+// blocked reports whether the snake would crash into something on its next
+// move.
+func (w *World) blocked(s *Snake) bool {
+	if s.Dir == DirNone {
+		return false
+	}
+	c := *s
+	c.Body = append([]Position(nil), s.Body...)
+	w.Advance(&c)
+	return w.collidesWithWall(&c) || w.collidesWithSelf(&c) || w.occupied(c.Head(), s)
+}
+
+func (w *World) needsToMoveSnake(s *Snake) bool {
+	return w.Timer%w.MoveTime(s) == 0
+}
+
+// Input is what one player did in one frame.
+type Input struct {
+	Dir      int  `json:"dir,omitempty"`
+	ToggleAI bool `json:"ai,omitempty"`
+	Reset    bool `json:"reset,omitempty"`
+}
+
+// Apply carries out a player's input. It is the only way players change the
+// world, so applying the same inputs to the same world always gives the same
+// result.
+func (w *World) Apply(id int, in Input) {
+	if in.Reset {
+		w.Reset(id)
+	}
+	if in.ToggleAI {
+		if s := w.Snake(id); s != nil {
+			s.AI = !s.AI
+		}
+	}
+	if in.Dir != DirNone {
+		w.SetDirection(id, in.Dir)
+	}
+}
+
+// Update advances the world by one frame and reports whether any snake moved.
+// It depends on nothing but the world itself.
+func (w *World) Update() bool {
+	if w.Over {
+		return false
+	}
+	w.updateFood()
+	if w.powerUps {
+		w.updatePowerUps()
+	}
+	moved := false
+	for _, s := range w.Snakes {
+		if s.Dead || !w.needsToMoveSnake(s) {
+			continue
+		}
+		moved = true
+		if s.AI {
+			w.AIMovement(s)
+		}
+
+		if w.collidesWithWall(s) || w.collidesWithSelf(s) || w.collidesWithOthers(s) {
+			if w.crash(s) {
+				continue
+			}
+		}
+
+		if w.collidesWithApple(s) {
+			w.placeApple()
+			w.AppleExpires = w.Clock + w.appleTimeout
+			w.eat(s, foodKind(FoodApple))
+		} else {
+			w.collidesWithFood(s)
+		}
+		w.collidesWithPowerUp(s)
+
+		if w.Practice && !s.AI && w.blocked(s) {
+			// The snake waits for the player to turn it somewhere
+			// else. It still counts as a crash.
+			s.Crashes++
+			s.Dir = DirNone
+			continue
+		}
+		w.Advance(s)
+	}
+
+	if w.Mode == ModeRoyale {
+		w.shrink()
+		w.checkWinner()
+	}
+	if IsChallenge(w.Mode) {
+		w.tickChallenge()
+	}
+
+	w.Timer++
+
+	return moved
+}
+
+func (w *World) placeApple() {
+	for {
+		w.Apple.X = w.rnd.Intn(w.Width - 1)
+		w.Apple.Y = w.rnd.Intn(w.Height - 1)
+		if !w.isWall(w.Apple) && w.portalAt(w.Apple) < 0 {
+			return
+		}
+	}
+}
+
+func (w *World) levelMoveTime(level int) int {
+	t := w.moveTime - (level - 1)
+	if t < 1 {
+		t = 1
+	}
+	return t
+}
+
+// Advance moves the snake one cell in its direction, around the board if it
+// wraps and through a portal if it meets one. A snake that is standing
+// still, stopped short by practice, keeps its body where it is.
+func (w *World) Advance(s *Snake) {
+	if s.Dir == DirNone {
+		return
+	}
+	s.Advance()
+	if len(s.Body) == 0 {
+		return
+	}
+	head := &s.Body[0]
+	if w.Wrap {
+		head.X = (head.X + w.Width) % w.Width
+		head.Y = (head.Y + w.Height) % w.Height
+	}
+	*head = w.Partner(*head)
+}
+
+// Advance moves the snake one cell in its direction.
+func (s *Snake) Advance() {
+	for i := len(s.Body) - 1; i > 0; i-- {
+		s.Body[i] = s.Body[i-1]
+	}
+	switch s.Dir {
+	case DirLeft:
+		s.Body[0].X--
+	case DirRight:
+		s.Body[0].X++
+	case DirDown:
+		s.Body[0].Y++
+	case DirUp:
+		s.Body[0].Y--
+	}
+}
+
+// Clone returns a copy of the world that shares no state with w.
+func (w *World) Clone() *World {
+	c := *w
+	c.Walls = append([]Position(nil), w.Walls...)
+	c.Portals = append([]Portal(nil), w.Portals...)
+	c.Food = append([]Food(nil), w.Food...)
+	c.PowerUps = append([]PowerUp(nil), w.PowerUps...)
+	c.wallSet = nil
+	c.Snakes = make([]*Snake, len(w.Snakes))
+	for i, s := range w.Snakes {
+		cs := *s
+		cs.Body = append([]Position(nil), s.Body...)
+		cs.Effects = append([]Effect(nil), s.Effects...)
+		c.Snakes[i] = &cs
+	}
+	return &c
+}
@@ -0,0 +1,248 @@
+package sim
+
+import "testing"
+
+// line returns a snake n cells long lying along row y, head at the right.
+func line(id, n, y int) *Snake {
+	s := &Snake{ID: id, Dir: DirRight, Level: 1, MoveTime: defaultMoveTime}
+	for i := 0; i < n; i++ {
+		s.Body = append(s.Body, Position{X: n - i, Y: y})
+	}
+	return s
+}
+
+// bots returns a world of cfg with n snakes the AI plays.
+func bots(cfg Config, n int) *World {
+	w := NewWorld(cfg)
+	for id := 1; id <= n; id++ {
+		s := w.AddSnake(id)
+		s.AI = true
+		s.Dir = DirRight
+	}
+	return w
+}
+
+var worldConfigs = []struct {
+	name string
+	cfg  Config
+	bots int
+}{
+	{"classic", Config{Width: 32, Height: 24, Seed: 1}, 1},
+	{"wrap", Config{Width: 32, Height: 24, Seed: 2, Wrap: true}, 2},
+	{"royale", Config{Width: 40, Height: 30, Seed: 3, Mode: ModeRoyale, ShrinkEvery: 120}, 4},
+	{"portals", Config{Width: 60, Height: 33, Seed: 4, Layout: LayoutPortals}, 2},
+	{"food", Config{Width: 48, Height: 36, Seed: 5, PowerUps: true, Food: DefaultFood}, 3},
+	{"timeattack", Config{Width: 32, Height: 24, Seed: 6, Mode: ModeTimeAttack, TimeLimit: 600}, 1},
+}
+
+func TestDeterminism(t *testing.T) {
+	for _, tc := range worldConfigs {
+		t.Run(tc.name, func(t *testing.T) {
+			a, b := bots(tc.cfg, tc.bots), bots(tc.cfg, tc.bots)
+			for i := 0; i < 2000 && !a.Over; i++ {
+				a.Update()
+				b.Update()
+				if a.Hash() != b.Hash() {
+					t.Fatalf("frame %d: worlds of the same seed went apart", a.Timer)
+				}
+			}
+		})
+	}
+}
+
+func TestClone(t *testing.T) {
+	for _, tc := range worldConfigs {
+		t.Run(tc.name, func(t *testing.T) {
+			w := bots(tc.cfg, tc.bots)
+			for i := 0; i < 300; i++ {
+				w.Update()
+			}
+			c := w.Clone()
+			if c.Hash() != w.Hash() {
+				t.Fatal("the clone hashes differently")
+			}
+			for i := 0; i < 1000 && !w.Over; i++ {
+				w.Update()
+				c.Update()
+				if c.Hash() != w.Hash() {
+					t.Fatalf("frame %d: the clone went its own way", w.Timer)
+				}
+			}
+
+			// Changing the clone leaves the world alone.
+			before := w.Hash()
+			c.Snakes[0].Body[0].X++
+			c.Snakes[0].Effects = append(c.Snakes[0].Effects, Effect{Kind: PowerDouble})
+			c.Food = append(c.Food[:0], Food{Kind: FoodGolden})
+			c.Update()
+			if w.Hash() != before {
+				t.Fatal("changing the clone changed the world")
+			}
+		})
+	}
+}
+
+func TestEat(t *testing.T) {
+	tests := []struct {
+		name      string
+		length    int
+		kind      string
+		double    bool
+		wantLen   int
+		wantScore int
+		wantLevel int
+	}{
+		{"apple", 3, FoodApple, false, 4, 1, 1},
+		{"apple to level 2", 10, FoodApple, false, 11, 1, 2},
+		{"apple to level 3", 20, FoodApple, false, 21, 1, 3},
+		{"golden", 3, FoodGolden, false, 4, 5, 1},
+		{"golden doubled", 3, FoodGolden, true, 4, 10, 1},
+		{"rabbit", 3, FoodRabbit, false, 5, 10, 1},
+		{"remains", 3, FoodRemains, false, 4, 1, 1},
+		{"poison", 5, FoodPoison, false, 2, 0, 1},
+		{"poison down to the head", 2, FoodPoison, false, 1, 0, 1},
+		{"poison back to level 1", 12, FoodPoison, false, 9, 0, 1},
+		{"poison back to level 2", 22, FoodPoison, false, 19, 0, 2},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := NewWorld(Config{Width: 32, Height: 24})
+			s := line(1, tc.length, 5)
+			w.Snakes = []*Snake{s}
+			w.relevel(s)
+			if tc.double {
+				s.Effects = []Effect{{Kind: PowerDouble, Until: 100}}
+			}
+			w.eat(s, foodKind(tc.kind))
+			if len(s.Body) != tc.wantLen || s.Score != tc.wantScore || s.Level != tc.wantLevel {
+				t.Errorf("length %d, score %d, level %d; want %d, %d, %d",
+					len(s.Body), s.Score, s.Level, tc.wantLen, tc.wantScore, tc.wantLevel)
+			}
+			if s.MoveTime != w.levelMoveTime(s.Level) {
+				t.Errorf("move time %d at level %d, want %d", s.MoveTime, s.Level, w.levelMoveTime(s.Level))
+			}
+			if s.Best != s.Score {
+				t.Errorf("best %d, want %d", s.Best, s.Score)
+			}
+		})
+	}
+}
+
+func TestLevels(t *testing.T) {
+	tests := []struct {
+		length   int
+		moveTime int
+		level    int
+		speed    int
+	}{
+		{1, 4, 1, 4},
+		{10, 4, 1, 4},
+		{11, 4, 2, 3},
+		{19, 4, 2, 3},
+		{21, 4, 3, 2},
+		{40, 4, 3, 2},
+		{11, 1, 2, 1},
+		{21, 2, 3, 1},
+	}
+	for _, tc := range tests {
+		w := NewWorld(Config{Width: 64, Height: 48, MoveTime: tc.moveTime})
+		s := line(1, tc.length, 5)
+		w.relevel(s)
+		if s.Level != tc.level || s.MoveTime != tc.speed {
+			t.Errorf("length %d at %d frames a cell: level %d, %d frames a cell; want %d, %d",
+				tc.length, tc.moveTime, s.Level, s.MoveTime, tc.level, tc.speed)
+		}
+	}
+}
+
+func TestShrink(t *testing.T) {
+	tests := []struct {
+		length  int
+		wantLen int
+		level   int
+	}{
+		{1, 1, 1},
+		{5, 3, 1},
+		{24, 12, 2},
+		{44, 22, 3},
+	}
+	for _, tc := range tests {
+		w := NewWorld(Config{Width: 64, Height: 48})
+		s := line(1, tc.length, 5)
+		w.relevel(s)
+		w.applyPowerUp(s, PowerShrink)
+		if len(s.Body) != tc.wantLen || s.Level != tc.level || s.MoveTime != w.levelMoveTime(tc.level) {
+			t.Errorf("shrinking %d: length %d, level %d, %d frames a cell; want %d, %d, %d",
+				tc.length, len(s.Body), s.Level, s.MoveTime, tc.wantLen, tc.level, w.levelMoveTime(tc.level))
+		}
+	}
+}
+
+func TestRoyaleWinner(t *testing.T) {
+	tests := []struct {
+		name   string
+		dead   []bool
+		leave  int
+		over   bool
+		winner int
+	}{
+		{"alone", []bool{false}, 0, false, 0},
+		{"alone and dead", []bool{true}, 0, true, 0},
+		{"two alive", []bool{false, false}, 0, false, 0},
+		{"one left", []bool{true, false}, 0, true, 2},
+		{"all dead", []bool{true, true, true}, 0, true, 0},
+		{"two of three alive", []bool{false, true, false}, 0, false, 0},
+		{"the other one left", []bool{false, false}, 2, true, 1},
+		{"leaving the dead", []bool{true, false, false}, 1, false, 0},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			w := NewWorld(Config{Width: 40, Height: 30, Mode: ModeRoyale})
+			for i, dead := range tc.dead {
+				s := w.AddSnake(i + 1)
+				if dead {
+					w.kill(s)
+				}
+			}
+			if tc.leave != 0 {
+				w.Leave(tc.leave)
+			}
+			w.Update()
+			if w.Over != tc.over || w.Winner != tc.winner {
+				t.Errorf("over %v, winner %d; want %v, %d", w.Over, w.Winner, tc.over, tc.winner)
+			}
+		})
+	}
+}
+
+func TestPractice(t *testing.T) {
+	w := NewWorld(Config{Width: 16, Height: 12, Practice: true})
+	s := line(1, 5, 5)
+	w.Snakes = []*Snake{s}
+	w.Apple = Position{X: 0, Y: 0}
+
+	// Heading right from x 5 the snake stops short of the edge.
+	for i := 0; i < 200; i++ {
+		w.Update()
+	}
+	want := []Position{{15, 5}, {14, 5}, {13, 5}, {12, 5}, {11, 5}}
+	if s.Dir != DirNone || s.Crashes != 1 || len(s.Body) != len(want) {
+		t.Fatalf("dir %d, %d crashes, length %d; want it stopped after one crash, 5 long",
+			s.Dir, s.Crashes, len(s.Body))
+	}
+	for i, p := range want {
+		if s.Body[i] != p {
+			t.Fatalf("body %v, want %v", s.Body, want)
+		}
+	}
+
+	// Turned somewhere else it goes on as it was.
+	w.Apply(s.ID, Input{Dir: DirDown})
+	for i := 0; i < 2*defaultMoveTime; i++ {
+		w.Update()
+	}
+	if len(s.Body) != len(want) || s.Crashes != 1 || s.Head() != (Position{15, 7}) {
+		t.Errorf("length %d, %d crashes, head at %v; want 5 long, 1 crash, at {15 7}",
+			len(s.Body), s.Crashes, s.Head())
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"math"
+
+	"ebiten/Snake/sim"
+
+	"github.com/hajimehoshi/ebiten"
+	"github.com/hajimehoshi/ebiten/audio"
+	"github.com/hajimehoshi/ebiten/audio/wav"
+	"github.com/hajimehoshi/ebiten/inpututil"
+)
+
+const sampleRate = 44100
+
+// cueFrames is how often the apple cue sounds, besides whenever an apple
+// turns up somewhere new.
+const cueFrames = 60
+
+// sounds plays the sound effects. Browsers only let a page make a sound
+// once the player has done something on it, so it starts on the first
+// key, click or touch, and until then there is nothing to play.
+type sounds struct {
+	context *audio.Context
+	crunch  []byte // eating
+	jab     []byte // crashing
+}
+
+var sfx sounds
+
+// interacted reports whether the player pressed anything this frame.
+func interacted() bool {
+	for k := ebiten.Key(0); k <= ebiten.KeyMax; k++ {
+		if inpututil.IsKeyJustPressed(k) {
+			return true
+		}
+	}
+	return inpututil.IsMouseButtonJustPressed(ebiten.MouseButtonLeft) ||
+		len(inpututil.JustPressedTouchIDs()) > 0
+}
+
+// updateSounds starts the sound on the first interaction and plays what
+// happened to the player's snake this frame.
+func (g *Game) updateSounds() {
+	if sfx.context == nil {
+		if !interacted() {
+			return
+		}
+		var err error
+		if sfx.context, err = audio.NewContext(sampleRate); err != nil {
+			log.Println(err)
+			return
+		}
+		sfx.crunch = decodeWav(sfx.context, CrunchSoundEffect)
+		sfx.jab = decodeWav(sfx.context, JabSoundEffect)
+	}
+
+	if g.world == nil {
+		g.lastCrashes = 0
+		return
+	}
+	p := g.player()
+	if p == nil {
+		g.lastCrashes = 0
+		return
+	}
+	if p.Score > g.lastScore {
+		play(sfx.crunch)
+	}
+	if p.Crashes > g.lastCrashes {
+		play(sfx.jab)
+	}
+	g.lastCrashes = p.Crashes
+
+	if g.prefs.AppleCues && !g.paused && !p.Dead && len(p.Body) > 0 && p.Dir != sim.DirNone &&
+		(g.frames%cueFrames == 0 || p.Score > g.lastScore) {
+		head, apple := p.Head(), g.world.Apple
+		play(appleCue(apple.X-head.X, apple.Y-head.Y))
+	}
+}
+
+// appleCue is a short beep that says where the apple is, dx and dy cells
+// from the head: from the left or the right as it lies left or right, up
+// to an octave higher above and lower below, and louder the nearer it is.
+func appleCue(dx, dy int) []byte {
+	clamp := func(v float64) float64 {
+		return math.Max(-1, math.Min(1, v))
+	}
+	pan := clamp(float64(dx) / 16)
+	freq := 440 * math.Pow(2, clamp(float64(-dy)/16))
+	far := math.Min(1, float64(abs(dx)+abs(dy))/48)
+	volume := 0.5 - 0.35*far
+
+	const n = sampleRate / 10
+	samples := make([]byte, 4*n)
+	// Equal power panning keeps it as loud in the middle as at the sides.
+	left, right := math.Cos((pan+1)*math.Pi/4), math.Sin((pan+1)*math.Pi/4)
+	for i := 0; i < n; i++ {
+		fade := math.Sin(math.Pi * float64(i) / n)
+		v := volume * fade * math.Sin(2*math.Pi*freq*float64(i)/sampleRate)
+		l, r := int16(v*left*math.MaxInt16), int16(v*right*math.MaxInt16)
+		samples[4*i], samples[4*i+1] = byte(l), byte(l>>8)
+		samples[4*i+2], samples[4*i+3] = byte(r), byte(r>>8)
+	}
+	return samples
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// decodeWav turns a wav file into samples the context can play.
+func decodeWav(context *audio.Context, data []byte) []byte {
+	s, err := wav.Decode(context, audio.BytesReadSeekCloser(data))
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	var b bytes.Buffer
+	if _, err := b.ReadFrom(s); err != nil {
+		log.Println(err)
+		return nil
+	}
+	return b.Bytes()
+}
+
+func play(samples []byte) {
+	if samples == nil {
+		return
+	}
+	p, err := audio.NewPlayerFromBytes(sfx.context, samples)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	p.Play()
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+
+	"ebiten/Snake/settings"
+	"ebiten/Snake/sim"
+
+	"github.com/hajimehoshi/ebiten"
+)
+
+// slowdowns are the speeds a local game can be slowed to, in frames per
+// frame of the game.
+var slowdowns = []int{1, 2, 4, 10}
+
+// historySize is how many movement ticks a local game can be rewound by.
+const historySize = 300
+
+// canStep reports whether the game may be paused, stepped, slowed down and
+// rewound. Challenges and replays have to run as they are recorded.
+func (g *Game) canStep() bool {
+	return g.client == nil && g.peer == nil && g.replay == nil && g.playback == nil &&
+		!sim.IsChallenge(g.world.Mode)
+}
+
+// stepControls handles the keys that pause, step, slow down and rewind a
+// local game, and reports whether the game runs this frame. By default
+//
+//	P      pause or go on, as does a tap
+//	.      pause and run up to the next movement tick
+//	,      pause and go back to the previous movement tick
+//	- =    slower, faster
+func (g *Game) stepControls() bool {
+	if !g.canStep() {
+		return true
+	}
+	switch {
+	case g.pressed(settings.ActionPause), g.tapped && !g.world.Over:
+		g.paused = !g.paused
+	case g.pressed(settings.ActionStep):
+		g.paused = true
+		g.stepTick()
+	case g.pressed(settings.ActionRewind):
+		g.paused = true
+		g.rewind()
+	case g.pressed(settings.ActionSlower):
+		if g.slow < len(slowdowns)-1 {
+			g.slow++
+		}
+	case g.pressed(settings.ActionFaster):
+		if g.slow > 0 {
+			g.slow--
+		}
+	}
+	return !g.paused && g.frames%slowdowns[g.slow] == 0 && g.assisted()
+}
+
+// updateWorld runs the local world one frame, keeping what it was before
+// every movement tick so it can be rewound, and reports whether any snake
+// moved.
+func (g *Game) updateWorld() bool {
+	var before *sim.World
+	if g.canStep() {
+		before = g.world.Clone()
+	}
+	moved := g.world.Update()
+	if !moved {
+		return false
+	}
+	if before != nil {
+		g.history = append(g.history, before)
+		if len(g.history) > historySize {
+			g.history = g.history[len(g.history)-historySize:]
+		}
+	}
+	if g.stream != nil {
+		g.stream.Publish(g.world)
+	}
+	return true
+}
+
+// stepTick runs the world on until the next frame a snake moves in. It
+// gives up after ten seconds of nobody moving.
+func (g *Game) stepTick() {
+	for i := 0; i < 10*ebiten.MaxTPS() && !g.world.Over; i++ {
+		g.wakeBots()
+		if g.updateWorld() {
+			return
+		}
+	}
+}
+
+// rewind goes back to just before the last movement tick.
+func (g *Game) rewind() {
+	n := len(g.history)
+	if n == 0 {
+		return
+	}
+	g.world, g.history = g.history[n-1], g.history[:n-1]
+	if g.stream != nil {
+		g.stream.Publish(g.world)
+	}
+}
+
+// stepStatus tells the HUD when the game is paused or slowed down.
+func (g *Game) stepStatus() string {
+	switch {
+	case g.paused:
+		return ", paused"
+	case g.slow > 0:
+		return fmt.Sprintf(", 1/%d speed", slowdowns[g.slow])
+	case g.speedAssist() < 100 && g.canStep():
+		return fmt.Sprintf(", %d%% speed", g.speedAssist())
+	}
+	return ""
+}
@@ -0,0 +1,15 @@
+// Package storage keeps the game's files: in ~/.snake on a computer, and in
+// the browser's localStorage under the same names when the game runs in one.
+package storage
+
+import "path/filepath"
+
+// Path returns where the named file is kept, "" when there is nowhere to
+// keep it.
+func Path(name string) string {
+	dir := Dir()
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, name)
+}
@@ -0,0 +1,71 @@
+//go:build js
+// +build js
+
+package storage
+
+import (
+	"fmt"
+	"os"
+	"syscall/js"
+)
+
+// localStorage returns the browser's localStorage, which private windows
+// and some embeddings don't have.
+func localStorage() (ls js.Value, ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	ls = js.Global().Get("localStorage")
+	return ls, ls.Truthy()
+}
+
+// Dir returns the prefix of the names the game keeps in localStorage, ""
+// when there is no localStorage.
+func Dir() string {
+	if _, ok := localStorage(); !ok {
+		return ""
+	}
+	return ".snake"
+}
+
+// ReadFile returns what localStorage has under name. A name it doesn't
+// have is an error os.IsNotExist recognises, as a missing file would be.
+func ReadFile(name string) ([]byte, error) {
+	ls, ok := localStorage()
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	v := ls.Call("getItem", name)
+	if v.IsNull() {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return []byte(v.String()), nil
+}
+
+// WriteFile puts data in localStorage under name.
+func WriteFile(name string, data []byte) (err error) {
+	ls, ok := localStorage()
+	if !ok {
+		return fmt.Errorf("%s: no localStorage", name)
+	}
+	defer func() {
+		// Like when it is full.
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%s: %v", name, r)
+		}
+	}()
+	ls.Call("setItem", name, string(data))
+	return nil
+}
+
+// Remove takes name out of localStorage.
+func Remove(name string) error {
+	ls, ok := localStorage()
+	if !ok {
+		return fmt.Errorf("%s: no localStorage", name)
+	}
+	ls.Call("removeItem", name)
+	return nil
+}
@@ -0,0 +1,39 @@
+//go:build !js
+// +build !js
+
+package storage
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Dir returns the directory the game keeps its files in, "" when there is
+// no home directory.
+func Dir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".snake")
+}
+
+// ReadFile returns what is in the named file.
+func ReadFile(name string) ([]byte, error) {
+	return ioutil.ReadFile(name)
+}
+
+// WriteFile replaces what is in the named file, making its directory first
+// if need be.
+func WriteFile(name string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name, data, 0644)
+}
+
+// Remove deletes the named file.
+func Remove(name string) error {
+	return os.Remove(name)
+}
@@ -0,0 +1,392 @@
+// Package theme holds the colours the board and the HUD are drawn in: the
+// built-in themes and the player's own, which live in a file next to the
+// settings.
+package theme
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"os"
+
+	"ebiten/Snake/sim"
+	"ebiten/Snake/storage"
+)
+
+// Sprite sets a theme can draw food with.
+const (
+	SpritesPictures = "pictures" // rabbits look like rabbits
+	SpritesPlain    = "plain"    // everything is a square of its colour
+)
+
+// Color is a colour written as "#rrggbb", or "#rrggbbaa" when it is see-through.
+type Color struct {
+	R, G, B, A uint8
+}
+
+func rgb(r, g, b uint8) Color {
+	return Color{r, g, b, 0xff}
+}
+
+// RGBA makes Color a color.Color.
+func (c Color) RGBA() (r, g, b, a uint32) {
+	return color.RGBA(c).RGBA()
+}
+
+// Visible reports whether anything is drawn in the colour at all.
+func (c Color) Visible() bool {
+	return c.A != 0
+}
+
+func (c Color) MarshalJSON() ([]byte, error) {
+	if c.A == 0xff {
+		return json.Marshal(fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B))
+	}
+	return json.Marshal(fmt.Sprintf("#%02x%02x%02x%02x", c.R, c.G, c.B, c.A))
+}
+
+func (c *Color) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	c.A = 0xff
+	n, _ := fmt.Sscanf(s, "#%02x%02x%02x%02x", &c.R, &c.G, &c.B, &c.A)
+	if n < 3 || len(s) != 7 && len(s) != 9 {
+		return fmt.Errorf("bad colour %q, want #rrggbb or #rrggbbaa", s)
+	}
+	return nil
+}
+
+// Blend returns the colour f of the way from a to b.
+func Blend(a, b Color, f float64) Color {
+	mix := func(x, y uint8) uint8 {
+		return uint8(float64(x) + (float64(y)-float64(x))*f + 0.5)
+	}
+	return Color{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), mix(a.A, b.A)}
+}
+
+// Snake is the colours of a snake: its head, and the rest of its body
+// going from Body behind the head to Tail at the end.
+type Snake struct {
+	Head Color `json:"head"`
+	Body Color `json:"body"`
+	Tail Color `json:"tail"`
+}
+
+// Segment returns the colour of part i of a body n long, the head being 0.
+func (s Snake) Segment(i, n int) Color {
+	switch {
+	case i == 0:
+		return s.Head
+	case n <= 2:
+		return s.Body
+	}
+	return Blend(s.Body, s.Tail, float64(i-1)/float64(n-2))
+}
+
+// HUD is the colours of the band above the board and of text on the board.
+type HUD struct {
+	Text  Color `json:"text"`
+	Dim   Color `json:"dim"`
+	Back  Color `json:"back"`
+	Popup Color `json:"popup"`
+}
+
+// Theme is a set of colours to draw the game in.
+type Theme struct {
+	Name string `json:"name"`
+
+	// Base is the theme a theme from the file starts from, so it only has
+	// to say what is different. Classic if none.
+	Base string `json:"base,omitempty"`
+
+	Background Color            `json:"background"`
+	Outside    Color            `json:"outside"` // beyond a board smaller than the screen
+	Grid       Color            `json:"grid"`    // lines between the cells, none if see-through
+	Path       Color            `json:"path"`    // the way the AI means to go
+	Player     Snake            `json:"player"`
+	Others     Snake            `json:"others"`
+	Walls      Color            `json:"walls"`
+	Apple      Color            `json:"apple"`
+	Food       map[string]Color `json:"food"`
+	PowerUps   map[string]Color `json:"powerUps"`
+	Portals    []Color          `json:"portals"` // one for each pair in turn
+	HUD        HUD              `json:"hud"`
+	Sprites    string           `json:"sprites"`
+}
+
+// copy returns a theme that can be changed without changing t.
+func (t *Theme) copy() *Theme {
+	c := *t
+	c.Food = map[string]Color{}
+	for k, v := range t.Food {
+		c.Food[k] = v
+	}
+	c.PowerUps = map[string]Color{}
+	for k, v := range t.PowerUps {
+		c.PowerUps[k] = v
+	}
+	c.Portals = append([]Color(nil), t.Portals...)
+	return &c
+}
+
+// Classic is the game as it has always looked.
+var Classic = &Theme{
+	Name:       "classic",
+	Background: rgb(0x00, 0x00, 0x00),
+	Outside:    rgb(0x30, 0x30, 0x30),
+	Path:       rgb(0x40, 0x40, 0x80),
+	Player:     Snake{rgb(0x80, 0xa0, 0xc0), rgb(0x80, 0xa0, 0xc0), rgb(0x80, 0xa0, 0xc0)},
+	Others:     Snake{rgb(0xa0, 0xc0, 0x80), rgb(0xa0, 0xc0, 0x80), rgb(0xa0, 0xc0, 0x80)},
+	Walls:      rgb(0x60, 0x60, 0x60),
+	Apple:      rgb(0xff, 0x00, 0x00),
+	Food: map[string]Color{
+		sim.FoodApple:   rgb(0xff, 0x00, 0x00),
+		sim.FoodGolden:  rgb(0xff, 0xd7, 0x00),
+		sim.FoodPoison:  rgb(0x80, 0x00, 0xc0),
+		sim.FoodRabbit:  rgb(0xc0, 0xc0, 0xc0),
+		sim.FoodRemains: rgb(0xff, 0xa0, 0x00),
+	},
+	PowerUps: map[string]Color{
+		sim.PowerSpeed:  rgb(0xff, 0xff, 0x00),
+		sim.PowerSlow:   rgb(0x00, 0xc0, 0xff),
+		sim.PowerGhost:  rgb(0xe0, 0xe0, 0xff),
+		sim.PowerDouble: rgb(0xff, 0x60, 0xff),
+		sim.PowerShrink: rgb(0x60, 0xff, 0xa0),
+	},
+	Portals: []Color{rgb(0x00, 0xe0, 0xe0), rgb(0xff, 0x80, 0x40), rgb(0x80, 0x80, 0xff)},
+	HUD:     HUD{rgb(0xe0, 0xe0, 0xe0), rgb(0x90, 0x90, 0x90), rgb(0x18, 0x18, 0x20), rgb(0xff, 0xff, 0x80)},
+	Sprites: SpritesPictures,
+}
+
+// Builtin lists the themes that come with the game, classic first.
+var Builtin = []*Theme{
+	Classic,
+	{
+		Name:       "light",
+		Background: rgb(0xf4, 0xf1, 0xe8),
+		Outside:    rgb(0xc8, 0xc4, 0xb8),
+		Grid:       rgb(0xe6, 0xe2, 0xd6),
+		Path:       rgb(0xc8, 0xd0, 0xf0),
+		Player:     Snake{rgb(0x1c, 0x3c, 0x78), rgb(0x30, 0x5c, 0xa0), rgb(0x90, 0xb0, 0xd8)},
+		Others:     Snake{rgb(0x28, 0x60, 0x1c), rgb(0x40, 0x88, 0x30), rgb(0xa8, 0xd0, 0x98)},
+		Walls:      rgb(0x70, 0x60, 0x50),
+		Apple:      rgb(0xd0, 0x10, 0x10),
+		Food: map[string]Color{
+			sim.FoodApple:   rgb(0xd0, 0x10, 0x10),
+			sim.FoodGolden:  rgb(0xd0, 0x98, 0x00),
+			sim.FoodPoison:  rgb(0x70, 0x00, 0xa8),
+			sim.FoodRabbit:  rgb(0x80, 0x78, 0x70),
+			sim.FoodRemains: rgb(0xe0, 0x70, 0x00),
+		},
+		PowerUps: map[string]Color{
+			sim.PowerSpeed:  rgb(0xe0, 0xb0, 0x00),
+			sim.PowerSlow:   rgb(0x00, 0x90, 0xd0),
+			sim.PowerGhost:  rgb(0xa0, 0xa0, 0xc8),
+			sim.PowerDouble: rgb(0xd0, 0x40, 0xd0),
+			sim.PowerShrink: rgb(0x20, 0xb0, 0x70),
+		},
+		Portals: []Color{rgb(0x00, 0xa0, 0xa0), rgb(0xe0, 0x60, 0x20), rgb(0x60, 0x60, 0xe0)},
+		HUD:     HUD{rgb(0x20, 0x20, 0x20), rgb(0x60, 0x60, 0x60), rgb(0xdc, 0xd8, 0xcc), rgb(0xc0, 0x50, 0x00)},
+		Sprites: SpritesPictures,
+	},
+	{
+		Name:       "retro",
+		Background: rgb(0x08, 0x14, 0x08),
+		Outside:    rgb(0x18, 0x28, 0x18),
+		Grid:       rgb(0x0c, 0x1c, 0x0c),
+		Path:       rgb(0x10, 0x38, 0x10),
+		Player:     Snake{rgb(0xb0, 0xff, 0x80), rgb(0x60, 0xe0, 0x40), rgb(0x20, 0x80, 0x20)},
+		Others:     Snake{rgb(0x80, 0xc0, 0x60), rgb(0x40, 0xa0, 0x30), rgb(0x18, 0x58, 0x18)},
+		Walls:      rgb(0x30, 0x60, 0x30),
+		Apple:      rgb(0xe0, 0xff, 0xa0),
+		Food: map[string]Color{
+			sim.FoodApple:   rgb(0xe0, 0xff, 0xa0),
+			sim.FoodGolden:  rgb(0xff, 0xff, 0x60),
+			sim.FoodPoison:  rgb(0x40, 0x60, 0x20),
+			sim.FoodRabbit:  rgb(0xa0, 0xd0, 0x90),
+			sim.FoodRemains: rgb(0xc0, 0xe0, 0x60),
+		},
+		PowerUps: map[string]Color{
+			sim.PowerSpeed:  rgb(0xf0, 0xff, 0x40),
+			sim.PowerSlow:   rgb(0x40, 0xc0, 0x80),
+			sim.PowerGhost:  rgb(0xd0, 0xff, 0xd0),
+			sim.PowerDouble: rgb(0x90, 0xff, 0x20),
+			sim.PowerShrink: rgb(0x20, 0xc0, 0x40),
+		},
+		Portals: []Color{rgb(0x80, 0xff, 0xc0), rgb(0xc0, 0xff, 0x40), rgb(0x40, 0xb0, 0x60)},
+		HUD:     HUD{rgb(0x90, 0xff, 0x70), rgb(0x40, 0xa0, 0x40), rgb(0x04, 0x0c, 0x04), rgb(0xe0, 0xff, 0xa0)},
+		Sprites: SpritesPlain,
+	},
+	{
+		// High contrast keeps to a few bright colours far apart on black,
+		// and squares rather than pictures.
+		Name:       "high-contrast",
+		Background: rgb(0x00, 0x00, 0x00),
+		Outside:    rgb(0x50, 0x50, 0x50),
+		Path:       rgb(0x00, 0x00, 0xa0),
+		Player:     Snake{rgb(0xff, 0xff, 0xff), rgb(0xff, 0xff, 0x00), rgb(0xff, 0xff, 0x00)},
+		Others:     Snake{rgb(0xff, 0xff, 0xff), rgb(0xff, 0x00, 0xff), rgb(0xff, 0x00, 0xff)},
+		Walls:      rgb(0xa0, 0xa0, 0xa0),
+		Apple:      rgb(0xff, 0x00, 0x00),
+		Food: map[string]Color{
+			sim.FoodApple:   rgb(0xff, 0x00, 0x00),
+			sim.FoodGolden:  rgb(0xff, 0x90, 0x00),
+			sim.FoodPoison:  rgb(0x00, 0xff, 0x00),
+			sim.FoodRabbit:  rgb(0xff, 0xff, 0xff),
+			sim.FoodRemains: rgb(0xff, 0x90, 0x00),
+		},
+		PowerUps: map[string]Color{
+			sim.PowerSpeed:  rgb(0x00, 0xff, 0xff),
+			sim.PowerSlow:   rgb(0x00, 0x80, 0xff),
+			sim.PowerGhost:  rgb(0xff, 0xff, 0xff),
+			sim.PowerDouble: rgb(0xff, 0x00, 0xff),
+			sim.PowerShrink: rgb(0x00, 0xff, 0x00),
+		},
+		Portals: []Color{rgb(0x00, 0xff, 0xff), rgb(0xff, 0x90, 0x00), rgb(0x00, 0x80, 0xff)},
+		HUD:     HUD{rgb(0xff, 0xff, 0xff), rgb(0xff, 0xff, 0x00), rgb(0x00, 0x00, 0x00), rgb(0xff, 0xff, 0x00)},
+		Sprites: SpritesPlain,
+	},
+	{
+		// The colour blind themes take their colours from the Okabe-Ito
+		// palette, which those with any of the common kinds of colour
+		// blindness can tell apart.
+		Name:       "colorblind",
+		Background: rgb(0x00, 0x00, 0x00),
+		Outside:    rgb(0x30, 0x30, 0x30),
+		Path:       rgb(0x10, 0x30, 0x50),
+		Player:     Snake{rgb(0xa8, 0xdc, 0xf8), rgb(0x56, 0xb4, 0xe9), rgb(0x00, 0x72, 0xb2)},
+		Others:     Snake{rgb(0xf8, 0xd0, 0x80), rgb(0xe6, 0x9f, 0x00), rgb(0x90, 0x64, 0x00)},
+		Walls:      rgb(0x80, 0x80, 0x80),
+		Apple:      rgb(0xd5, 0x5e, 0x00),
+		Food: map[string]Color{
+			sim.FoodApple:   rgb(0xd5, 0x5e, 0x00),
+			sim.FoodGolden:  rgb(0xf0, 0xe4, 0x42),
+			sim.FoodPoison:  rgb(0xcc, 0x79, 0xa7),
+			sim.FoodRabbit:  rgb(0xff, 0xff, 0xff),
+			sim.FoodRemains: rgb(0x00, 0x9e, 0x73),
+		},
+		PowerUps: map[string]Color{
+			sim.PowerSpeed:  rgb(0xf0, 0xe4, 0x42),
+			sim.PowerSlow:   rgb(0x00, 0x72, 0xb2),
+			sim.PowerGhost:  rgb(0xff, 0xff, 0xff),
+			sim.PowerDouble: rgb(0xcc, 0x79, 0xa7),
+			sim.PowerShrink: rgb(0x00, 0x9e, 0x73),
+		},
+		Portals: []Color{rgb(0x00, 0x9e, 0x73), rgb(0xcc, 0x79, 0xa7), rgb(0xf0, 0xe4, 0x42)},
+		HUD:     HUD{rgb(0xe0, 0xe0, 0xe0), rgb(0x90, 0x90, 0x90), rgb(0x18, 0x18, 0x20), rgb(0xf0, 0xe4, 0x42)},
+		Sprites: SpritesPictures,
+	},
+	{
+		Name:       "colorblind-light",
+		Background: rgb(0xf8, 0xf8, 0xf8),
+		Outside:    rgb(0xc8, 0xc8, 0xc8),
+		Grid:       rgb(0xe8, 0xe8, 0xe8),
+		Path:       rgb(0xd0, 0xe4, 0xf4),
+		Player:     Snake{rgb(0x00, 0x3c, 0x60), rgb(0x00, 0x72, 0xb2), rgb(0x56, 0xb4, 0xe9)},
+		Others:     Snake{rgb(0x70, 0x4c, 0x00), rgb(0xb0, 0x78, 0x00), rgb(0xe6, 0x9f, 0x00)},
+		Walls:      rgb(0x40, 0x40, 0x40),
+		Apple:      rgb(0xd5, 0x5e, 0x00),
+		Food: map[string]Color{
+			sim.FoodApple:   rgb(0xd5, 0x5e, 0x00),
+			sim.FoodGolden:  rgb(0xc0, 0xb0, 0x00),
+			sim.FoodPoison:  rgb(0xcc, 0x79, 0xa7),
+			sim.FoodRabbit:  rgb(0x00, 0x00, 0x00),
+			sim.FoodRemains: rgb(0x00, 0x9e, 0x73),
+		},
+		PowerUps: map[string]Color{
+			sim.PowerSpeed:  rgb(0xc0, 0xb0, 0x00),
+			sim.PowerSlow:   rgb(0x56, 0xb4, 0xe9),
+			sim.PowerGhost:  rgb(0x90, 0x90, 0x90),
+			sim.PowerDouble: rgb(0xcc, 0x79, 0xa7),
+			sim.PowerShrink: rgb(0x00, 0x9e, 0x73),
+		},
+		Portals: []Color{rgb(0x00, 0x9e, 0x73), rgb(0xcc, 0x79, 0xa7), rgb(0xc0, 0xb0, 0x00)},
+		HUD:     HUD{rgb(0x10, 0x10, 0x10), rgb(0x50, 0x50, 0x50), rgb(0xe0, 0xe0, 0xe0), rgb(0xd5, 0x5e, 0x00)},
+		Sprites: SpritesPlain,
+	},
+}
+
+// Path returns where the player's own themes are kept, "" when there is no
+// home directory to keep them in.
+func Path() string {
+	return storage.Path("themes.json")
+}
+
+// Load returns the built-in themes followed by the player's own from the
+// file at path, a list of them. A theme with the name of a built-in one
+// takes its place. Without a file there are only the built-in themes.
+func Load(path string) ([]*Theme, error) {
+	themes := append([]*Theme(nil), Builtin...)
+	if path == "" {
+		return themes, nil
+	}
+	data, err := storage.ReadFile(path)
+	if os.IsNotExist(err) {
+		return themes, nil
+	}
+	if err != nil {
+		return themes, err
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return themes, fmt.Errorf("%s: %v", path, err)
+	}
+	for _, r := range raw {
+		var head struct {
+			Name string `json:"name"`
+			Base string `json:"base"`
+		}
+		if err := json.Unmarshal(r, &head); err != nil {
+			return themes, fmt.Errorf("%s: %v", path, err)
+		}
+		if head.Name == "" {
+			return themes, fmt.Errorf("%s: a theme has no name", path)
+		}
+		base := Classic
+		if head.Base != "" {
+			if base = Find(themes, head.Base); base.Name != head.Base {
+				return themes, fmt.Errorf("%s: theme %s is based on %s, which there is none of", path, head.Name, head.Base)
+			}
+		}
+		t := base.copy()
+		if err := json.Unmarshal(r, t); err != nil {
+			return themes, fmt.Errorf("%s: theme %s: %v", path, head.Name, err)
+		}
+		if len(t.Portals) == 0 {
+			return themes, fmt.Errorf("%s: theme %s has no colours for portals", path, t.Name)
+		}
+		replaced := false
+		for i, old := range themes {
+			if old.Name == t.Name {
+				themes[i], replaced = t, true
+			}
+		}
+		if !replaced {
+			themes = append(themes, t)
+		}
+	}
+	return themes, nil
+}
+
+// Find returns the theme called name, or the first one if there is none.
+func Find(themes []*Theme, name string) *Theme {
+	for _, t := range themes {
+		if t.Name == name {
+			return t
+		}
+	}
+	return themes[0]
+}
+
+// Next returns the theme step places on from the one called name, going
+// round from the last to the first and back.
+func Next(themes []*Theme, name string, step int) *Theme {
+	for i, t := range themes {
+		if t.Name == name {
+			return themes[(i+step+len(themes))%len(themes)]
+		}
+	}
+	return themes[0]
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"image/color"
+
+	"ebiten/Snake/sim"
+
+	"github.com/hajimehoshi/ebiten"
+	"github.com/hajimehoshi/ebiten/ebitenutil"
+	"github.com/hajimehoshi/ebiten/inpututil"
+)
+
+const (
+	// swipeDistance is how far a finger has to move to turn the snake.
+	swipeDistance = 30
+
+	// tapFrames is how long a touch that doesn't move may last to be a tap.
+	tapFrames = 15
+
+	// dpadButton is the size of a button of the on-screen D-pad, which sits
+	// in the bottom right corner.
+	dpadButton = 48
+)
+
+var (
+	dpadColor   = color.RGBA{0xff, 0xff, 0xff, 0x30}
+	dpadPressed = color.RGBA{0xff, 0xff, 0xff, 0x70}
+)
+
+// touch is a finger on the screen: where a swipe is measured from, the
+// frame it came down in and what it has done so far.
+type touch struct {
+	x, y  int
+	frame int
+	moved bool
+	dpad  bool
+}
+
+// updateTouches turns this frame's touches into a turn, a swipe or a press
+// on the D-pad, or a tap, for readInput and stepControls to pick up.
+func (g *Game) updateTouches() {
+	g.touchDir, g.tapped = sim.DirNone, false
+	if g.touches == nil {
+		g.touches = map[int]*touch{}
+	}
+	pressed := inpututil.JustPressedTouchIDs()
+	for _, id := range pressed {
+		x, y := g.touchPosition(id)
+		t := &touch{x: x, y: y, frame: g.frames}
+		if dir := g.dpadAt(x, y); dir != sim.DirNone {
+			t.dpad, g.touchDir = true, dir
+		}
+		g.touches[id] = t
+	}
+	g.touched = g.touched || len(pressed) > 0
+	for id, t := range g.touches {
+		if inpututil.IsTouchJustReleased(id) {
+			if !t.moved && !t.dpad && g.frames-t.frame <= tapFrames {
+				g.tapped = true
+			}
+			delete(g.touches, id)
+			continue
+		}
+		if t.dpad {
+			continue
+		}
+		// Every swipe distance along the way turns again, so one finger
+		// can steer around a corner and back without lifting.
+		x, y := g.touchPosition(id)
+		if dir := swipe(x-t.x, y-t.y); dir != sim.DirNone {
+			t.x, t.y, t.moved = x, y, true
+			g.touchDir = dir
+		}
+	}
+}
+
+// swipe returns the way a finger that moved by dx, dy swiped, if it moved
+// far enough.
+func swipe(dx, dy int) int {
+	adx, ady := dx, dy
+	if adx < 0 {
+		adx = -adx
+	}
+	if ady < 0 {
+		ady = -ady
+	}
+	switch {
+	case adx < swipeDistance && ady < swipeDistance:
+		return sim.DirNone
+	case adx > ady && dx < 0:
+		return sim.DirLeft
+	case adx > ady:
+		return sim.DirRight
+	case dy < 0:
+		return sim.DirUp
+	}
+	return sim.DirDown
+}
+
+// showDPad reports whether the on-screen D-pad is up: once the screen has
+// been touched, unless the player would rather swipe.
+func (g *Game) showDPad() bool {
+	return g.touched && g.prefs.TouchDPad && g.keyScreen == nil
+}
+
+// dpadButtons returns where the buttons of the D-pad are on the screen.
+func dpadButtons() map[int][2]int {
+	cx, cy := screenWidth-2*dpadButton, hudHeight+screenHeight-2*dpadButton
+	return map[int][2]int{
+		sim.DirUp:    {cx, cy - dpadButton},
+		sim.DirDown:  {cx, cy + dpadButton},
+		sim.DirLeft:  {cx - dpadButton, cy},
+		sim.DirRight: {cx + dpadButton, cy},
+	}
+}
+
+// dpadAt returns the D-pad button at x, y on the screen, if any.
+func (g *Game) dpadAt(x, y int) int {
+	if !g.showDPad() {
+		return sim.DirNone
+	}
+	for dir, b := range dpadButtons() {
+		if x >= b[0] && x < b[0]+dpadButton && y >= b[1] && y < b[1]+dpadButton {
+			return dir
+		}
+	}
+	return sim.DirNone
+}
+
+func (g *Game) drawDPad(screen *ebiten.Image) {
+	if !g.showDPad() {
+		return
+	}
+	held := map[int]bool{}
+	for _, id := range ebiten.TouchIDs() {
+		held[g.dpadAt(g.touchPosition(id))] = true
+	}
+	for dir, b := range dpadButtons() {
+		c := dpadColor
+		if held[dir] {
+			c = dpadPressed
+		}
+		ebitenutil.DrawRect(screen, float64(b[0]+2), float64(b[1]+2), dpadButton-4, dpadButton-4, c)
+	}
+}
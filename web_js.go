@@ -0,0 +1,45 @@
+//go:build js
+// +build js
+
+package main
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"syscall/js"
+
+	"github.com/hajimehoshi/ebiten"
+)
+
+// pageArgs turns the query of the page's address into flags, so that
+// index.html?mode=sprint&length=50 is the same as -mode sprint -length 50
+// and index.html?powerups the same as -powerups.
+func pageArgs() []string {
+	q, err := url.ParseQuery(strings.TrimPrefix(js.Global().Get("location").Get("search").String(), "?"))
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for name := range q {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var args []string
+	for _, name := range names {
+		for _, v := range q[name] {
+			if v == "" {
+				args = append(args, "-"+name)
+			} else {
+				args = append(args, "-"+name+"="+v)
+			}
+		}
+	}
+	return args
+}
+
+// waitForFocus reports whether the game waits for the player to come back
+// to the tab. A local game does, unless spectators are watching it.
+func (g *Game) waitForFocus() bool {
+	return !ebiten.IsFocused() && g.client == nil && g.peer == nil && g.spectator == nil && g.stream == nil
+}
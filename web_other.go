@@ -0,0 +1,16 @@
+//go:build !js
+// +build !js
+
+package main
+
+// pageArgs returns the flags the page's address gives, none outside a
+// browser.
+func pageArgs() []string {
+	return nil
+}
+
+// waitForFocus reports whether the game waits for the player to come back
+// to the tab, which outside a browser it never does.
+func (g *Game) waitForFocus() bool {
+	return false
+}